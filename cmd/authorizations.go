@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	jh "github.com/jdrivas/sponde/jupyterhub"
+	t "github.com/jdrivas/sponde/term"
+	"github.com/juju/ansiterm"
+)
+
+// TokenOwner is a proxy for jh.TokenOwner
+type TokenOwner jh.TokenOwner
+
+// Describe displays the user or service that owns a token,
+// its roles and the token's scopes.
+func (to TokenOwner) Describe() {
+	owner := jh.TokenOwner(to)
+	lines := [][2]string{
+		{"Name:", checkForEmptyString(owner.Name())},
+		{"Kind:", checkForEmptyString(owner.Kind)},
+		{"Admin:", fmt.Sprintf("%t", owner.Admin())},
+	}
+	if owner.User != nil {
+		lines = append(lines, [2]string{"Groups:", joinOrEmpty(owner.User.Groups)})
+	}
+	lines = append(lines, [2]string{"Roles:", joinOrEmpty(owner.Roles())})
+	w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+	for _, l := range lines {
+		fmt.Fprintf(w, "%s\t%s\n", t.Title(l[0]), t.Text("%s", l[1]))
+	}
+	w.Flush()
+
+	scopes := append([]string(nil), owner.Scopes()...)
+	if len(scopes) > 0 {
+		sort.Strings(scopes)
+		fmt.Println()
+		fmt.Printf("%s\n", t.Title("Scopes"))
+		fmt.Printf("%s\n", t.Text("%s", strings.Join(scopes, "\n")))
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	jh "github.com/jdrivas/sponde/jupyterhub"
+	t "github.com/jdrivas/sponde/term"
+	"github.com/juju/ansiterm"
+)
+
+// listBatch displays a line for each request of a batch, with what the
+// name column holds (e.g. "User"), and done saying what a request that worked
+// did (e.g. "started"). Then a count of the failures, if there were any.
+func listBatch(column string, results jh.BatchResults, done func(jh.BatchResult) string) {
+	w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+	fmt.Fprintf(w, "%s\n", t.Title("%s\tStatus\tResult", column))
+	for _, r := range results {
+		status := "<none>"
+		if r.Resp != nil {
+			status = httpStatusFunc(r.StatusCode())("%s", r.Resp.Status)
+		}
+		result := t.Success("%s", done(r))
+		if r.Err != nil {
+			result = t.Fail("%s", batchError(r.Err))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.Text("%s", r.Name), status, result)
+	}
+	w.Flush()
+
+	if failed := results.Failed(); len(failed) > 0 {
+		fmt.Printf("\n%s\n", t.Fail("%d of %d failed.", len(failed), len(results)))
+	}
+}
+
+// batchError is a short explanation of err for a batch listing,
+// the hub's own message if it has one.
+func batchError(err error) string {
+	var hubErr *jh.HubError
+	if errors.As(err, &hubErr) {
+		if hubErr.Message != "" {
+			return hubErr.Message
+		}
+		return hubErr.Status
+	}
+	return err.Error()
+}
+
+// serverDone says what a server start or stop that worked did:
+// done (e.g. "started"), or "pending" if the hub is still at it.
+func serverDone(done string) func(jh.BatchResult) string {
+	return func(r jh.BatchResult) string {
+		if r.StatusCode() == http.StatusAccepted {
+			return "pending"
+		}
+		return done
+	}
+}
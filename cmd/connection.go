@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	jh "github.com/jdrivas/sponde/jupyterhub"
 	t "github.com/jdrivas/sponde/term"
@@ -24,6 +25,11 @@ var lastConnection Connection
 
 // var defaultConnection Connection
 
+// commandContext is the context requests are made with for the command
+// currently executing. In interactive mode it is canceled on an interrupt,
+// see runInterruptible.
+var commandContext = context.Background()
+
 // List displpays the list of connections and notes the current one.
 func (conns ConnectionList) List() {
 	if len(conns) > 0 {
@@ -63,9 +69,17 @@ func getAllConnections() ConnectionList {
 }
 
 // GetCurrentConnection returns the current connection object for
-// the JupyterhHub API
+// the JupyterhHub API, making its requests with the commandContext,
+// logging them as debug and verbose say, and caching them as
+// --offline and --max-age say.
 func getCurrentConnection() Connection {
-	return currentConnection.copy()
+	conn := currentConnection.copy()
+	*conn.Connection = conn.Connection.WithContext(commandContext)
+	conn.Logger = hubLogger()
+	conn.Cache = responseCache(conn.HubURL)
+	conn.Offline = offlineFV
+	conn.MaxAge = maxAge()
+	return conn
 }
 
 // SetCurrentConneciton sets the connection
@@ -121,19 +135,41 @@ func updateConnection(update, existing Connection) Connection {
 	if update.Token != "" {
 		conn.Token = update.Token
 	}
-	if conn.Auth.ClientID != "" {
-		conn.Auth.ClientID = update.Auth.ClientID
-	}
-	if conn.Auth.ClientSecret != "" {
-		conn.Auth.ClientSecret = update.Auth.ClientSecret
-	}
-	if conn.Auth.RedirectURL != "" {
-		conn.Auth.RedirectURL = update.Auth.RedirectURL
+	conn.Transport = updateTransport(update.Transport, existing.Transport)
+	// Zero is a meaningful retry setting, so take the update's policy whole.
+	conn.Retry = update.Retry
+	if update.Concurrency != 0 {
+		conn.Concurrency = update.Concurrency
 	}
+	conn.Auth.UpdateAuth(update.Auth)
 
 	return conn
 }
 
+// updateTransport only changes the transport settings that are set in update,
+// except InsecureSkipVerify which is always taken from update, so that
+// --insecure=false can turn it off.
+func updateTransport(update, existing jh.Transport) jh.Transport {
+	tr := existing
+	if update.CAFile != "" {
+		tr.CAFile = update.CAFile
+	}
+	if update.CertFile != "" {
+		tr.CertFile = update.CertFile
+	}
+	if update.KeyFile != "" {
+		tr.KeyFile = update.KeyFile
+	}
+	tr.InsecureSkipVerify = update.InsecureSkipVerify
+	if update.ProxyURL != "" {
+		tr.ProxyURL = update.ProxyURL
+	}
+	if update.Timeout != 0 {
+		tr.Timeout = update.Timeout
+	}
+	return tr
+}
+
 // In addition we allow creation of named connections
 // in a configuration file that's managed by Viper.
 
@@ -177,6 +213,18 @@ const (
 	clientIDKey                = "clientID"
 	clientSecretKey            = "clientSecret"
 	redirectURLKey             = "redirectURL"
+	caFileKey                  = "caFile"
+	clientCertKey              = "clientCert"
+	clientKeyKey               = "clientKey"
+	insecureSkipVerifyKey      = "insecureSkipVerify"
+	proxyURLKey                = "proxyURL"
+	timeoutKey                 = "timeout"
+	retryKey                   = "retry"
+	maxRetriesKey              = "maxRetries"
+	initialBackoffKey          = "initialBackoff"
+	maxBackoffKey              = "maxBackoff"
+	retryNonIdempotentKey      = "retryNonIdempotent"
+	concurrencyKey             = "concurrency"
 )
 
 // Read in the config to get all the named connections
@@ -207,6 +255,16 @@ func getConnectionFromConfig(name string) (conn Connection, ok bool) {
 					ClientID:     viper.GetString(fmt.Sprintf("%s.%s.%s", connKey, authKey, clientIDKey)),
 					RedirectURL:  viper.GetString(fmt.Sprintf("%s.%s.%s", connKey, authKey, redirectURLKey)),
 				},
+				Transport: jh.Transport{
+					CAFile:             viper.GetString(fmt.Sprintf("%s.%s", connKey, caFileKey)),
+					CertFile:           viper.GetString(fmt.Sprintf("%s.%s", connKey, clientCertKey)),
+					KeyFile:            viper.GetString(fmt.Sprintf("%s.%s", connKey, clientKeyKey)),
+					InsecureSkipVerify: viper.GetBool(fmt.Sprintf("%s.%s", connKey, insecureSkipVerifyKey)),
+					ProxyURL:           viper.GetString(fmt.Sprintf("%s.%s", connKey, proxyURLKey)),
+					Timeout:            viper.GetDuration(fmt.Sprintf("%s.%s", connKey, timeoutKey)),
+				},
+				Retry:       getRetryPolicyFromConfig(fmt.Sprintf("%s.%s", connKey, retryKey)),
+				Concurrency: viper.GetInt(fmt.Sprintf("%s.%s", connKey, concurrencyKey)),
 			},
 		}
 		ok = true
@@ -214,6 +272,25 @@ func getConnectionFromConfig(name string) (conn Connection, ok bool) {
 	return conn, ok
 }
 
+// getRetryPolicyFromConfig starts from the default policy and
+// replaces whatever is set in the config at retryKey.
+func getRetryPolicyFromConfig(retryKey string) (rp jh.RetryPolicy) {
+	rp = jh.DefaultRetryPolicy
+	if k := fmt.Sprintf("%s.%s", retryKey, maxRetriesKey); viper.IsSet(k) {
+		rp.MaxRetries = viper.GetInt(k)
+	}
+	if k := fmt.Sprintf("%s.%s", retryKey, initialBackoffKey); viper.IsSet(k) {
+		rp.InitialBackoff = viper.GetDuration(k)
+	}
+	if k := fmt.Sprintf("%s.%s", retryKey, maxBackoffKey); viper.IsSet(k) {
+		rp.MaxBackoff = viper.GetDuration(k)
+	}
+	if k := fmt.Sprintf("%s.%s", retryKey, retryNonIdempotentKey); viper.IsSet(k) {
+		rp.RetryNonIdempotent = viper.GetBool(k)
+	}
+	return rp
+}
+
 // initConnections sets up the first current Connection,
 // initializes the ShowTokens state, and should be called whenever the Viper config file gets reloaded.
 // Since we need at least a URL to break and/or let us know that no token has been set. Also, this value
@@ -234,23 +311,24 @@ func initConnections() {
 			defaultName := viper.GetString(defaultConnectionNameKey)
 			if defaultName != "" {
 				conn, ok = getConnection(defaultName)
-				if !ok {
-					// ... As a last resort set up a broken empty connection.
-					// We won't panic here as we can set it during interactive
-					// mode and it will otherwise error.
-					conn = Connection{
-						Connection: &jh.Connection{
-							Name:   defaultConnectionNameValue,
-							HubURL: defaultHubURL,
-							Token:  "",
-							Auth: jh.Auth{
-								ClientID:     "",
-								ClientSecret: "",
-								RedirectURL:  "",
-							},
-						},
-					}
-				}
+			}
+		}
+		if !ok {
+			// ... As a last resort set up a broken empty connection.
+			// We won't panic here as we can set it during interactive
+			// mode (or with flags) and it will otherwise error.
+			conn = Connection{
+				Connection: &jh.Connection{
+					Name:   defaultConnectionNameValue,
+					HubURL: defaultHubURL,
+					Token:  "",
+					Auth: jh.Auth{
+						ClientID:     "",
+						ClientSecret: "",
+						RedirectURL:  "",
+					},
+					Retry: jh.DefaultRetryPolicy,
+				},
 			}
 		}
 		lastConnection = conn
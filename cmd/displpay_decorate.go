@@ -3,11 +3,13 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
 
+	jh "github.com/jdrivas/sponde/jupyterhub"
 	t "github.com/jdrivas/sponde/term"
 	"github.com/juju/ansiterm"
 )
@@ -120,6 +122,10 @@ func displpayServerStopedF(stopped bool, resp *http.Response, err error) func()
 // private API
 func render(renderer func(), resp *http.Response, err error) {
 
+	if !cacheBannerShown {
+		cacheBanner(resp)
+	}
+	cacheBannerShown = false
 	switch {
 	case Debug():
 		httpDecorate((errorDecorate(renderer, err)), resp)()
@@ -127,7 +133,8 @@ func render(renderer func(), resp *http.Response, err error) {
 		shortHTTPDecorate((errorDecorate(renderer, err)), resp)()
 	default:
 
-		if err == nil {
+		// Version and offline errors come from sponde, not the hub, so there's no response to show.
+		if err == nil || jh.IsVersionError(err) || errors.Is(err, jh.ErrOffline) {
 			errorDecorate(renderer, err)()
 		} else {
 			errorHTTPDecorate((errorDecorate(renderer, err)), resp)()
@@ -142,7 +149,7 @@ func render(renderer func(), resp *http.Response, err error) {
 func errorDecorate(f func(), err error) func() {
 	return (func() {
 		if err != nil {
-			fmt.Printf(fmt.Sprintf("%s\n", t.Error(err)))
+			fmt.Printf("%s\n", t.Error(err))
 		}
 
 		f()
@@ -172,20 +179,14 @@ func shortHTTPDecorate(f func(), resp *http.Response) func() {
 	})
 }
 
+// The hub's message, if there was one, is carried in the jh.HubError
+// and so gets displayed by the errorDecorate.
 func errorHTTPDecorate(f func(), resp *http.Response) func() {
 	return (func() {
 		if resp == nil {
 			nilResp()
 		} else {
 			fmt.Printf("%s %s\n", t.Title("HTTP Response: "), httpStatusFunc(resp.StatusCode)("%s", resp.Status))
-			body, err := ioutil.ReadAll(resp.Body)
-			resp.Body.Close()
-			if err == nil && resp.StatusCode != http.StatusNoContent {
-				m, err := getMessage(body)
-				if err == nil && m.Message != "" {
-					fmt.Printf("%s %s\n", t.Title("Message:"), t.Alert(m.Message))
-				}
-			}
 		}
 
 		f()
@@ -193,7 +194,8 @@ func errorHTTPDecorate(f func(), resp *http.Response) func() {
 }
 
 // Message is a simple struct to pull out JSON that is often
-// embedded in error returns.
+// embedded in responses. Errors from the jupyterhub package carry
+// it already (see jh.HubError), this is for the raw HTTP commands.
 type Message struct {
 	Status  int    `json:"status"`
 	Message string `json:"message"`
@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 
 	jh "github.com/jdrivas/sponde/jupyterhub"
 	t "github.com/jdrivas/sponde/term"
 	"github.com/juju/ansiterm"
+	"github.com/spf13/cobra"
 )
 
 // Groups are lists of groups of users kept on the hub.
@@ -23,13 +25,52 @@ func (groups Groups) List() {
 		for _, g := range groups {
 			fmt.Fprintf(w, "%s\n", t.SubTitle("%s\t%s\t%v", g.Name, g.Kind, g.UserNames))
 		}
+		w.Flush()
 	} else {
-		fmt.Fprintf(w, "%s\n", t.Title("Name\tKind\t# Users"))
-		for _, g := range groups {
-			fmt.Fprintf(w, "%s\n", t.SubTitle("%s\t%s\t%d", g.Name, g.Kind, len(g.UserNames)))
+		groups.listPage(newPagedTable(), true)
+	}
+}
+
+// listPage prints the groups with a count of their users, optionally
+// with a header, in the columns of table. Used to stream pages of groups
+// as they arrive, with the same table for each page.
+func (groups Groups) listPage(table *pagedTable, header bool) {
+	titles := []string{"Name", "Kind", "# Users"}
+	var rows [][]string
+	for _, g := range groups {
+		rows = append(rows, []string{g.Name, g.Kind, fmt.Sprintf("%d", len(g.UserNames))})
+	}
+	if header {
+		table.fit(titles)
+		table.fit(rows...)
+		fmt.Printf("%s\n", t.Title("%s", table.line(titles)))
+	}
+	for _, row := range rows {
+		fmt.Printf("%s\n", t.SubTitle("%s", table.line(row)))
+	}
+}
+
+// streamGroups displays all of the groups on the hub, pageSizeFV at a time.
+// If they all fit on one page they're displayed with List.
+func streamGroups(conn Connection) {
+	count := 0
+	table := newPagedTable(16, 5)
+	resp, err := conn.EachGroupsPage(pageSizeFV, func(groups jh.Groups, p jh.Pagination) error {
+		pageCacheBanner(p)
+		if count == 0 && p.Next == nil {
+			Groups(groups).List()
+		} else {
+			Groups(groups).listPage(table, count == 0)
+		}
+		count += len(groups)
+		return nil
+	})
+	display := func() {
+		if count == 0 && err == nil {
+			fmt.Printf("There were no groups.\n")
 		}
 	}
-	w.Flush()
+	DisplayF(display, resp, err)
 }
 
 // Describe is a more detailed description of a group
@@ -47,6 +88,17 @@ func (group Group) Describe() {
 		fmt.Fprintf(w, "\t\t%s\n", t.SubTitle(name))
 	}
 	w.Flush()
+
+	fmt.Println()
+	if len(group.Properties) == 0 {
+		fmt.Printf("No Properties\n")
+	} else {
+		fmt.Printf("Properties\n")
+		w = ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+		fmt.Fprintf(w, "%s\n", t.Title("Key\tValue"))
+		fprintProperties(w, group.Properties, "")
+		w.Flush()
+	}
 }
 
 // UserGroup captures the changes for an update.
@@ -59,3 +111,75 @@ func (userGroup UserGroup) List() {
 	fmt.Fprintf(w, "%s\n", t.SubTitle("%s\t%v", userGroup.Name, userGroup.UserNames))
 	w.Flush()
 }
+
+// groupsFV are the groups to add a user to, or remove them from.
+var groupsFV []string
+
+// groupMembersArgs checks the arguments of add user and remove user: users
+// and then the group, or with --group just the user.
+func groupMembersArgs(cmd *cobra.Command, args []string) error {
+	if len(groupsFV) > 0 {
+		return cobra.ExactArgs(1)(cmd, args)
+	}
+	return cobra.MinimumNArgs(2)(cmd, args)
+}
+
+// changeGroupMembers adds users to (or removes them from) a group. Without groups, the
+// last of args is the group and the rest are users. With groups, args is a user who's
+// added to (or removed from) each of them, in a batch.
+func changeGroupMembers(args, groups []string, remove bool) {
+	conn := getCurrentConnection()
+	if len(groups) > 0 {
+		user := args[0]
+		if remove {
+			listBatch("Group", conn.RemoveUserFromGroups(user, groups), func(jh.BatchResult) string { return "removed " + user })
+		} else {
+			listBatch("Group", conn.AddUserToGroups(user, groups), func(jh.BatchResult) string { return "added " + user })
+		}
+		return
+	}
+
+	ug := jh.UserGroup{
+		Name:      args[len(args)-1],
+		UserNames: args[:len(args)-1],
+	}
+	var userGroup jh.UserGroup
+	var resp *http.Response
+	var err error
+	if remove {
+		userGroup, resp, err = conn.RemoveUserFromGroup(ug)
+	} else {
+		userGroup, resp, err = conn.AddUserToGroup(ug)
+	}
+	List(UserGroup(userGroup), resp, err)
+}
+
+// removePropsFV are the group properties to remove.
+var removePropsFV []string
+
+// setGroupProperties updates the properties of the group name: setting those in keyValues
+// (see parseKeyValues) and removing those named in remove.
+func setGroupProperties(name string, keyValues, remove []string) {
+	updates, err := parseKeyValues(keyValues)
+	if err != nil {
+		cmdError(err)
+		return
+	}
+
+	conn := getCurrentConnection()
+	group, resp, err := fresh(conn).GetGroup(name)
+	if err == nil {
+		props := group.Properties
+		if props == nil {
+			props = make(map[string]interface{})
+		}
+		for k, v := range updates {
+			props[k] = v
+		}
+		for _, k := range remove {
+			delete(props, k)
+		}
+		group, resp, err = conn.SetGroupProperties(name, props)
+	}
+	Describe(Group(group), resp, err)
+}
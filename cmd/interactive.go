@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/chzyer/readline"
@@ -80,18 +82,47 @@ func resetEnvironment() {
 	// TODO: The connection handling logicis is
 	// a disaster. Fix it.
 	initConnectionWithFlags()
+
+	// Only offer what the hub can do.
+	hideUnsupported(rootCmd)
 }
 
 // Parse the line and execute the command
 func doICommand(line string) (err error) {
 
 	rootCmd.SetArgs(strings.Split(line, " "))
-	err = rootCmd.Execute()
+	runInterruptible(func() {
+		err = rootCmd.Execute()
+	})
 
 	resetEnvironment()
 	return err
 }
 
+// runInterruptible runs f with a commandContext that is canceled
+// by an interrupt (Ctrl-C). This stops any request in flight rather
+// than killing the interactive session.
+func runInterruptible(f func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+	go func() {
+		select {
+		case <-sigs:
+			fmt.Println()
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	commandContext = ctx
+	defer func() { commandContext = context.Background() }()
+	f()
+}
+
 func promptLoop(process func(string) error) (err error) {
 
 	// Set up for the first itme through.
@@ -142,6 +173,7 @@ func statusDisplay() (s string) {
 // DoInteractive sets up a readline loop that reads and executes comands.
 func DoInteractive() {
 	readline.SetHistoryPath("./.sponde_history")
+	interactiveSession = true
 	xICommand := func(line string) (err error) { return doICommand(line) }
 	err := promptLoop(xICommand)
 	if err != nil {
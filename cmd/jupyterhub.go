@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	jh "github.com/jdrivas/sponde/jupyterhub"
 	t "github.com/jdrivas/sponde/term"
@@ -46,6 +47,31 @@ func buildJupyterHub(mode runMode) {
 
 	listCmd.AddCommand(listConnsCmd)
 
+	loginCmd := &cobra.Command{
+		Use:   "login [flags] [<connection-name>]",
+		Short: "Log in to the Hub with OAuth and keep the token.",
+		Long: `Logs in to the hub of <connection-name> (or the current connection) in a browser, 
+and saves the new token as the connection's token in the config file.
+Login uses the OAuth client in the connection's auth settings (clientID, clientSecret, 
+redirectURL) or --client-id, --client-secret and --auth-redirect-url. The client, e.g. a 
+service made with create service, must be registered with the hub using the same redirect URL, 
+which must be on this machine (default is ` + jh.DefaultRedirectURL + `).
+If the connection isn't in the config file the token is displayed instead, this
+is the only time it will be displayed.`,
+		Example:               "  sponde login --client-id service-sponde staging",
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			login(name)
+		},
+	}
+	loginCmd.Flags().BoolVarP(&noBrowserFV, noBrowserFlagKey, "", false, "don't open a browser, just display the URL to log in at.")
+	rootCmd.AddCommand(loginCmd)
+
 	//
 	// Hub Commands
 	//
@@ -65,10 +91,10 @@ func buildJupyterHub(mode runMode) {
 		Use:   "info",
 		Short: "Hub operational details.",
 		Long:  "Returns detailed information about the running Hub",
-		Run: func(cmd *cobra.Command, args []string) {
+		Run: repeatable(func(cmd *cobra.Command, args []string) {
 			info, resp, err := getCurrentConnection().GetInfo()
 			List(Info(info), resp, err)
-		},
+		}),
 	})
 
 	rootCmd.AddCommand(&cobra.Command{
@@ -78,12 +104,9 @@ func buildJupyterHub(mode runMode) {
 		Run: func(cmd *cobra.Command, args []string) {
 			resp, err := getCurrentConnection().Shutdown()
 			display := func() {
-				var result string
-				switch resp.StatusCode {
-				case http.StatusAccepted:
+				result := t.Fail("Probably not shutting down.")
+				if err == nil && resp.StatusCode == http.StatusAccepted {
 					result = t.Success("shutting down.")
-				default:
-					result = t.Fail("Probably not shutting down.")
 				}
 				fmt.Printf("%s %s\n", t.Title("Hub is"), result)
 			}
@@ -92,30 +115,77 @@ func buildJupyterHub(mode runMode) {
 	})
 
 	// Proxy Routes
+	listProxy := repeatable(func(cmd *cobra.Command, args []string) {
+		routes, resp, err := getCurrentConnection().GetProxy()
+		List(Routes(routes), resp, err)
+	})
 	var proxyCmd = &cobra.Command{
+		Use:     "proxy",
+		Aliases: []string{"routes"},
+		Short:   "The proxy's routing table, and managing it",
+		Long: `Returns the routing table from the JupyterHub proxy.
+Use the sub-commands to have the hub bring the proxy up to date.`,
+		Args: cobra.NoArgs,
+		Run:  listProxy,
+	}
+	rootCmd.AddCommand(proxyCmd)
+
+	listCmd.AddCommand(&cobra.Command{
 		Use:     "proxy",
 		Aliases: []string{"routes"},
 		Short:   "The proxy's routing table",
 		Long:    "Returns the routing table from the JupyterHub proxy",
-		Args: func(cmd *cobra.Command, args []string) error {
-			return nil
+		Args:    cobra.NoArgs,
+		Run:     listProxy,
+	})
+
+	proxyCmd.AddCommand(&cobra.Command{
+		Use:   "sync",
+		Short: "Sync the proxy's routes with the hub.",
+		Long: `Has the hub add and remove routes on the proxy until they match 
+the hub's servers and services. The routing table is shown before and after.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			changeProxy("sync", getCurrentConnection().SyncProxy)
 		},
+	})
+
+	updateProxyCmd := &cobra.Command{
+		Use:   "update [flags]",
+		Short: "Point the hub at a new proxy.",
+		Long: `Tells the hub the proxy's new API --url and/or --auth-token, 
+e.g. after the proxy has restarted, and has the hub sync the proxy's routes. 
+The routing table is shown before and after.
+The proxy's token is --auth-token, rather than --token which is the token 
+for the hub.`,
+		Example: "  sponde proxy update --url http://proxy-api:8001 --auth-token $CONFIGPROXY_AUTH_TOKEN",
+		Args:    cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			routes, resp, err := getCurrentConnection().GetProxy()
-			List(Routes(routes), resp, err)
+			if proxyAPIURLFV == "" && proxyAuthTokenFV == "" {
+				cmdError(fmt.Errorf("nothing to update, use --%s and/or --%s", proxyAPIURLFlagKey, proxyAuthTokenFlagKey))
+				return
+			}
+			update := jh.ProxyUpdate{APIURL: proxyAPIURLFV, AuthToken: proxyAuthTokenFV}
+			changeProxy("update", func() (*http.Response, error) {
+				return getCurrentConnection().UpdateProxy(update)
+			})
 		},
 	}
-	rootCmd.AddCommand(proxyCmd)
-	listCmd.AddCommand(proxyCmd)
+	updateProxyCmd.Flags().StringVarP(&proxyAPIURLFV, proxyAPIURLFlagKey, "", "", "the proxy's API URL (e.g. http://proxy-api:8001).")
+	updateProxyCmd.Flags().StringVarP(&proxyAuthTokenFV, proxyAuthTokenFlagKey, "", "", "the token the hub uses with the proxy's API.")
+	proxyCmd.AddCommand(updateProxyCmd)
 
 	// Users
 	var listUsersCmd = &cobra.Command{
 		Use:   "users [<user-id> ...]",
 		Short: "Users accessing the hub.",
 		Long: `Returns a list of users from the connected Hub, 
-or if users are specified, data on those users`,
-		Run: doUsers(listUsers),
+or if users are specified, data on those users.
+With --state only users in that state are listed.`,
+		Run: repeatable(doUsers(listUsers, listUsersPage)),
 	}
+	listUsersCmd.Flags().IntVarP(&pageSizeFV, pageSizeFlagKey, "", jh.DefaultPageSize, "number of users to get from the hub at a time.")
+	listUsersCmd.Flags().StringVarP(&stateFV, stateFlagKey, "", "", "only users whose servers are: active, inactive or ready.")
 	listCmd.AddCommand(listUsersCmd)
 
 	var describeUsersCmd = &cobra.Command{
@@ -125,10 +195,64 @@ or if users are specified, data on those users`,
 		Short:                 "Hub users.",
 		Long: `Returns a longer description of hub users.
 If no user-id is provided then all Hub users are described.`,
-		Run: doUsers(describeUsers),
+		Run: doUsers(describeUsers, describeUsersPage),
 	}
+	describeUsersCmd.Flags().IntVarP(&pageSizeFV, pageSizeFlagKey, "", jh.DefaultPageSize, "number of users to get from the hub at a time.")
+	describeUsersCmd.Flags().StringVarP(&stateFV, stateFlagKey, "", "", "only users whose servers are: active, inactive or ready.")
 	describeCmd.AddCommand(describeUsersCmd)
 
+	createUsersCmd := &cobra.Command{
+		Use:     "user [flags] <user-id> ...",
+		Aliases: []string{"users"},
+		Short:   "Create users on the hub.",
+		Long: `Creates each of the users <user-id> ... on the hub, as admins with --admin.
+More user-ids can be read from a file with --file, or from stdin with "--file -",
+one or more per line.`,
+		Example: "  sponde create user alice bob\n  sponde create user --file roster.txt",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && namesFileFV == "" {
+				return fmt.Errorf("requires at least one user-id or --file")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			names, err := readNames(args, namesFileFV)
+			if err != nil {
+				cmdError(err)
+				return
+			}
+			createUsers(names, adminFV)
+		},
+	}
+	createUsersCmd.Flags().BoolVarP(&adminFV, adminFlagKey, "", false, "create the users as admins.")
+	createUsersCmd.Flags().StringVarP(&namesFileFV, fileFlagKey, "f", "", "read more user-ids from this file (\"-\" for stdin).")
+	createCmd.AddCommand(createUsersCmd)
+
+	deleteUsersCmd := &cobra.Command{
+		Use:     "user [flags] <user-id> ...",
+		Aliases: []string{"users"},
+		Short:   "Delete users from the hub.",
+		Long: `Deletes each of the users <user-id> ... from the hub.
+More user-ids can be read from a file with --file, or from stdin with "--file -",
+one or more per line.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && namesFileFV == "" {
+				return fmt.Errorf("requires at least one user-id or --file")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			names, err := readNames(args, namesFileFV)
+			if err != nil {
+				cmdError(err)
+				return
+			}
+			deleteUsers(names)
+		},
+	}
+	deleteUsersCmd.Flags().StringVarP(&namesFileFV, fileFlagKey, "f", "", "read more user-ids from this file (\"-\" for stdin).")
+	deleteCmd.AddCommand(deleteUsersCmd)
+
 	var updateUsersCmd = &cobra.Command{
 		Use:   "user",
 		Short: "Change the name or admin status of an existing user.",
@@ -170,38 +294,111 @@ If no user-id is provided then all Hub users are described.`,
 	})
 
 	// User Severs
-	startCmd.AddCommand(&cobra.Command{
-		Use:   "server <user-id>",
-		Short: "Starts a users notebook server.",
-		Long:  "Starts a users notebook server and will tell you if the server has started or pending starting on return.",
-		Args:  cobra.MinimumNArgs(1),
+	startServerCmd := &cobra.Command{
+		Use:   "server [flags] <user-id> ...",
+		Short: "Starts users notebook servers.",
+		Long: `Starts a users notebook server and will tell you if the server has started or pending starting on return.
+A pending server's progress is followed until it's ready, unless --no-wait is given.
+Spawner options, like a profile, image or resources, can be given with 
+--option, --options-file and --profile.
+The servers of more than one user are started together, up to --concurrency at a time, 
+without following their progress. More user-ids can be read from a file with --file, 
+or from stdin with "--file -".`,
+		Example: `  sponde start server --profile gpu alice
+  sponde start server -o image=jupyter/scipy-notebook -o cpu_limit=2 alice
+  sponde start server --file roster.txt`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && namesFileFV == "" {
+				return fmt.Errorf("requires at least one user-id or --file")
+			}
+			return nil
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			started, resp, err := getCurrentConnection().StartServer(args[0])
-			DisplayF(displayServerStartedF(started, resp, err), resp, err)
+			options, err := userOptions()
+			if err != nil {
+				cmdError(err)
+				return
+			}
+			names, err := readNames(args, namesFileFV)
+			if err != nil {
+				cmdError(err)
+				return
+			}
+			conn := getCurrentConnection()
+			if len(names) > 1 {
+				listBatch("User", conn.StartServers(names, options), serverDone("started"))
+				return
+			}
+			watchServerStart(
+				func() (bool, *http.Response, error) { return conn.StartServer(names[0], options) },
+				func(f func(jh.ProgressEvent) error) (*http.Response, error) {
+					return conn.WatchServerProgress(names[0], f)
+				})
 		},
-	})
+	}
+	startServerCmd.Flags().BoolVarP(&noWaitFV, noWaitFlagKey, "", false, "don't wait for the server to be ready.")
+	startServerCmd.Flags().StringArrayVarP(&optionsFV, optionFlagKey, "o", nil, "start with the spawner option key=value (may be repeated).")
+	startServerCmd.Flags().StringVarP(&optionsFileFV, optionsFileFlagKey, "", "", "start with the spawner options in this JSON file.")
+	startServerCmd.Flags().StringVarP(&profileFV, profileFlagKey, "", "", "start with this spawner profile (e.g. a KubeSpawner profile slug).")
+	startServerCmd.Flags().StringVarP(&namesFileFV, fileFlagKey, "f", "", "read more user-ids from this file (\"-\" for stdin).")
+	startCmd.AddCommand(startServerCmd)
 
-	stopCmd.AddCommand(&cobra.Command{
-		Use:   "server <user-id>",
-		Short: "Stops a users notebook server.",
-		Long:  "Stops a users notebook server and will tell you if the server has stopped or is pending stop on return.",
-		Args:  cobra.MinimumNArgs(1),
+	stopServerCmd := &cobra.Command{
+		Use:   "server [flags] <user-id> ...",
+		Short: "Stops users notebook servers.",
+		Long: `Stops a users notebook server and will tell you if the server has stopped or is pending stop on return.
+The servers of more than one user are stopped together, up to --concurrency at a time.
+More user-ids can be read from a file with --file, or from stdin with "--file -".`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && namesFileFV == "" {
+				return fmt.Errorf("requires at least one user-id or --file")
+			}
+			return nil
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			stopped, resp, err := getCurrentConnection().StopServer(args[0])
-			DisplayF(displayServerStartedF(stopped, resp, err), resp, err)
+			names, err := readNames(args, namesFileFV)
+			if err != nil {
+				cmdError(err)
+				return
+			}
+			conn := getCurrentConnection()
+			if len(names) > 1 {
+				listBatch("User", conn.StopServers(names), serverDone("stopped"))
+				return
+			}
+			stopped, resp, err := conn.StopServer(names[0])
+			DisplayF(displpayServerStopedF(stopped, resp, err), resp, err)
 		},
-	})
+	}
+	stopServerCmd.Flags().StringVarP(&namesFileFV, fileFlagKey, "f", "", "read more user-ids from this file (\"-\" for stdin).")
+	stopCmd.AddCommand(stopServerCmd)
 
-	startCmd.AddCommand(&cobra.Command{
+	startNamedServerCmd := &cobra.Command{
 		Use:   "named-server <user-id> <server-name>",
 		Short: "Start a named server",
-		Long:  "Start a named server for a user.",
-		Args:  cobra.MinimumNArgs(2),
+		Long: `Start a named server for a user.
+A pending server's progress is followed until it's ready, unless --no-wait is given.
+Spawner options can be given with --option, --options-file and --profile.`,
+		Args: cobra.MinimumNArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
-			started, resp, err := getCurrentConnection().StartNamedServer(args[0], args[1])
-			DisplayF(displayServerStartedF(started, resp, err), resp, err)
+			options, err := userOptions()
+			if err != nil {
+				cmdError(err)
+				return
+			}
+			conn := getCurrentConnection()
+			watchServerStart(
+				func() (bool, *http.Response, error) { return conn.StartNamedServer(args[0], args[1], options) },
+				func(f func(jh.ProgressEvent) error) (*http.Response, error) {
+					return conn.WatchNamedServerProgress(args[0], args[1], f)
+				})
 		},
-	})
+	}
+	startNamedServerCmd.Flags().BoolVarP(&noWaitFV, noWaitFlagKey, "", false, "don't wait for the server to be ready.")
+	startNamedServerCmd.Flags().StringArrayVarP(&optionsFV, optionFlagKey, "o", nil, "start with the spawner option key=value (may be repeated).")
+	startNamedServerCmd.Flags().StringVarP(&optionsFileFV, optionsFileFlagKey, "", "", "start with the spawner options in this JSON file.")
+	startNamedServerCmd.Flags().StringVarP(&profileFV, profileFlagKey, "", "", "start with this spawner profile (e.g. a KubeSpawner profile slug).")
+	startCmd.AddCommand(startNamedServerCmd)
 
 	stopCmd.AddCommand(&cobra.Command{
 		Use:   "named-server <server-id> <server-name>",
@@ -214,6 +411,76 @@ If no user-id is provided then all Hub users are described.`,
 		},
 	})
 
+	listCmd.AddCommand(&cobra.Command{
+		Use:     "servers <user-id>",
+		Aliases: []string{"server"},
+		Short:   "A user's servers.",
+		Long:    "Lists the running and pending servers, default and named, of the user <user-id>.",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			listServers(args[0])
+		},
+	})
+
+	describeCmd.AddCommand(&cobra.Command{
+		Use:   "server <user-id> [<server-name>]",
+		Short: "Details of one of a user's servers.",
+		Long: `Describes the server <server-name> of the user <user-id>, 
+or the user's default server if no <server-name> is given.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			serverName := ""
+			if len(args) > 1 {
+				serverName = args[1]
+			}
+			server, resp, err := getCurrentConnection().GetServer(args[0], serverName)
+			display := func() {
+				if err == nil {
+					Server(server).Describe()
+				}
+			}
+			DisplayF(display, resp, err)
+		},
+	})
+
+	deleteCmd.AddCommand(&cobra.Command{
+		Use:   "named-server <user-id> <server-name>",
+		Short: "Delete a named server.",
+		Long: `Stops the named server <server-name> of the user <user-id> and removes it,
+so that it no longer counts towards the hub's limit on named servers.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			deleted, resp, err := getCurrentConnection().DeleteNamedServer(args[0], args[1])
+			display := func() {
+				result := t.Success("deleted")
+				if !deleted {
+					result = t.Success("delete requested")
+					if err != nil {
+						result = t.Fail("probably not deleted")
+					}
+				}
+				fmt.Printf("%s %s\n", t.Title("Server"), result)
+			}
+			DisplayF(display, resp, err)
+		},
+	})
+
+	reportActivityCmd := &cobra.Command{
+		Use:   "report-activity [flags] <user-id>",
+		Short: "Tell the hub a user's servers are in use.",
+		Long: `Reports the user <user-id> as active now, so that their servers aren't culled as idle.
+Specific servers can be reported with --server ("" for the default server).
+With --every the activity is reported again each period until stopped.`,
+		Example: `  sponde report-activity alice --server batch --every 5m`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			reportActivity(args[0], activityServersFV, activityEveryFV)
+		},
+	}
+	reportActivityCmd.Flags().StringArrayVarP(&activityServersFV, serverFlagKey, "", nil, "report this server as active too (may be repeated).")
+	reportActivityCmd.Flags().DurationVarP(&activityEveryFV, everyFlagKey, "", 0, "keep reporting activity this often (e.g. 5m) until stopped.")
+	rootCmd.AddCommand(reportActivityCmd)
+
 	// User Tokens
 	listTokensCmd := &cobra.Command{
 		Use:     "tokens <user-id>",
@@ -235,18 +502,23 @@ The API, and so this command does not actually obtain the token itself.`,
 		Short: "Create an API token for a user.",
 		Long: `Creates a new API token for <user-id> with identifying text <note> 
 (all text typed after the <user-id> is taken as a the text of the note.).
+The token can be limited with --expires-in, and on JupyterHub 2+ 
+given only the permissions of --scope and --role (both may be repeated).
 
 NOTE: This will display a token independently of the show-tokens command or any settings. 
 This is the only place where this token will be displayed and you cannot get it back 
 any other way. So, write it down if you intend to use it.`,
+		Example:               "  sponde create token --expires-in 24h --scope read:users --scope list:users grader CI grading job",
 		DisableFlagsInUseLine: true,
 		Args:                  cobra.MinimumNArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
 			name := args[0]
 			notes := strings.Join(args[1:], " ")
-			tokenTemplate := jh.APIToken{
-				User: name,
-				Note: notes,
+			tokenTemplate := jh.TokenRequest{
+				Note:      notes,
+				ExpiresIn: int(expiresInFV.Seconds()),
+				Scopes:    scopesFV,
+				Roles:     rolesFV,
 			}
 			token, resp, err := getCurrentConnection().CreateToken(name, tokenTemplate)
 			display := func() {
@@ -258,6 +530,9 @@ any other way. So, write it down if you intend to use it.`,
 			DisplayF(display, resp, err)
 		},
 	}
+	createTokenCmd.Flags().DurationVarP(&expiresInFV, expiresInFlagKey, "", 0, "the token expires after this long (e.g. 24h). (default is never)")
+	createTokenCmd.Flags().StringSliceVarP(&scopesFV, scopeFlagKey, "", nil, "limit the token to this scope (e.g. read:users).")
+	createTokenCmd.Flags().StringSliceVarP(&rolesFV, roleFlagKey, "", nil, "give the token this role.")
 	createCmd.AddCommand(createTokenCmd)
 
 	deleteTokenCmd := &cobra.Command{
@@ -284,18 +559,148 @@ any other way. So, write it down if you intend to use it.`,
 	}
 	describeCmd.AddCommand(describeTokenCmd)
 
+	// Sharing servers
+	listCmd.AddCommand(&cobra.Command{
+		Use:         "shares <owner>[/<server-name>]",
+		Annotations: requires(jh.ServerSharing),
+		Short:       "Shares of a user's servers.",
+		Long: `Lists who <owner> has shared their servers with, or just <owner>'s 
+server <server-name> (<owner>/ for the default server). Sharing needs JupyterHub 5+.`,
+		Example: "  sponde list shares david/ml",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			listShares(args[0])
+		},
+	})
+
+	listCmd.AddCommand(&cobra.Command{
+		Use:         "shared <user-id>",
+		Annotations: requires(jh.ServerSharing),
+		Short:       "Servers shared with a user.",
+		Long:        "Lists the servers that have been shared with <user-id>. Sharing needs JupyterHub 5+.",
+		Args:        cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			shares, resp, err := getCurrentConnection().GetSharedWith(args[0])
+			List(Shares(shares), resp, err)
+		},
+	})
+
+	shareServerCmd := &cobra.Command{
+		Use:         "server [flags] <owner>[/<server-name>]",
+		Annotations: requires(jh.ServerSharing),
+		Short:       "Share a user's server with users and groups.",
+		Long: `Shares <owner>'s default server, or their server <server-name>, with each --with-user 
+and --with-group (both may be repeated). Limit what they can do with --scope, 
+the default is to use the server. Sharing needs JupyterHub 5+.`,
+		Example:               "  sponde share server student1/ee201 --with-user ta1 --with-group ee201-tas",
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			changeShares(args[0], false)
+		},
+	}
+	shareServerCmd.Flags().StringSliceVarP(&shareUsersFV, withUserFlagKey, "", nil, "share with this user.")
+	shareServerCmd.Flags().StringSliceVarP(&shareGroupsFV, withGroupFlagKey, "", nil, "share with this group.")
+	shareServerCmd.Flags().StringSliceVarP(&scopesFV, scopeFlagKey, "", nil, "share only this scope.")
+	shareCmd.AddCommand(shareServerCmd)
+
+	revokeShareCmd := &cobra.Command{
+		Use:         "share [flags] <owner>[/<server-name>]",
+		Annotations: requires(jh.ServerSharing),
+		Short:       "Stop sharing a user's server.",
+		Long: `Revokes the shares of <owner>'s default server, or their server <server-name>, 
+with each --with-user and --with-group, or every share of it with --all.
+With --scope only those scopes are taken away.`,
+		Example:               "  sponde revoke share student1/ee201 --with-user ta1",
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			changeShares(args[0], true)
+		},
+	}
+	revokeShareCmd.Flags().StringSliceVarP(&shareUsersFV, withUserFlagKey, "", nil, "revoke the share with this user.")
+	revokeShareCmd.Flags().StringSliceVarP(&shareGroupsFV, withGroupFlagKey, "", nil, "revoke the share with this group.")
+	revokeShareCmd.Flags().StringSliceVarP(&scopesFV, scopeFlagKey, "", nil, "revoke only this scope.")
+	revokeShareCmd.Flags().BoolVarP(&allSharesFV, allFlagKey, "", false, "revoke all of the server's shares.")
+	revokeCmd.AddCommand(revokeShareCmd)
+
+	listCmd.AddCommand(&cobra.Command{
+		Use:         "share-codes <owner>[/<server-name>]",
+		Annotations: requires(jh.ServerSharing),
+		Aliases:     []string{"share-code"},
+		Short:       "Share codes of a user's server.",
+		Long:        "Lists the share codes of <owner>'s default server, or their server <server-name>.",
+		Args:        cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			owner, servername := parseServerSpec(args[0])
+			codes, resp, err := getCurrentConnection().GetShareCodes(owner, servername)
+			List(ShareCodes(codes), resp, err)
+		},
+	})
+
+	createShareCodeCmd := &cobra.Command{
+		Use:         "share-code [flags] <owner>[/<server-name>]",
+		Annotations: requires(jh.ServerSharing),
+		Short:       "Create a code to share a user's server.",
+		Long: `Creates a share code for <owner>'s default server, or their server <server-name>,
+and displays the URL to accept it at. Anyone who accepts the code gets a share 
+of the server, until the code expires (see --expires-in).
+
+NOTE: This is the only time the code will be displayed.`,
+		Example:               "  sponde create share-code student1/ee201 --expires-in 24h",
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			createShareCode(args[0])
+		},
+	}
+	createShareCodeCmd.Flags().DurationVarP(&expiresInFV, expiresInFlagKey, "", 0, "the code expires after this long (e.g. 24h). (default is the hub's)")
+	createCmd.AddCommand(createShareCodeCmd)
+
+	deleteShareCodeCmd := &cobra.Command{
+		Use:         "share-code [flags] <owner>[/<server-name>] [<code-id>]",
+		Annotations: requires(jh.ServerSharing),
+		Short:       "Revoke a share code.",
+		Long: `Revokes the share code <code-id> (see list share-codes) of <owner>'s default server, 
+or their server <server-name>, or all of the server's codes with --all.
+Shares already accepted with the code are not revoked.`,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			id := ""
+			if len(args) > 1 {
+				id = args[1]
+			}
+			deleteShareCode(args[0], id)
+		},
+	}
+	deleteShareCodeCmd.Flags().BoolVarP(&allSharesFV, allFlagKey, "", false, "revoke all of the server's share codes.")
+	deleteCmd.AddCommand(deleteShareCodeCmd)
+
 	// Hub Tokens
 
-	// TODO: This currently only gets users back
-	// and needs to get Services as well and then display correctly.
 	getCmd.AddCommand(&cobra.Command{
 		Use:   "owner <hub-token>",
 		Short: "Identify a user or service from a Hub API token.",
-		Long:  "Returns and displays a user or service from a Hub API token.",
-		Args:  cobra.MinimumNArgs(1),
+		Long: `Returns and displays a user or service from a Hub API token.
+This uses an API that was deprecated in JupyterHub 2, on newer hubs
+use whoami with the token instead.`,
+		Args: cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			user, resp, err := getCurrentConnection().GetTokenOwner(args[0])
-			Describe(UserList(UserList{user}), resp, err)
+			owner, resp, err := getCurrentConnection().GetTokenOwner(args[0])
+			Describe(TokenOwner(owner), resp, err)
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "whoami",
+		Short: "The user or service the connection's token belongs to.",
+		Long: `Displays the user or service that owns the current connection's token,
+its roles, and the scopes the token actually carries (JupyterHub 2+).`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			owner, resp, err := getCurrentConnection().Whoami()
+			Describe(TokenOwner(owner), resp, err)
 		},
 	})
 
@@ -314,15 +719,16 @@ any other way. So, write it down if you intend to use it.`,
 	})
 	*/
 	// Groups
-	listCmd.AddCommand(&cobra.Command{
+	listGroupsCmd := &cobra.Command{
 		Use:   "groups",
 		Short: "Groups registered with the Hub.",
 		Long:  "Returns details of the groups that are defined with this Hub.",
-		Run: func(cmd *cobra.Command, args []string) {
-			groups, resp, err := getCurrentConnection().GetGroups()
-			List(Groups(groups), resp, err)
-		},
-	})
+		Run: repeatable(func(cmd *cobra.Command, args []string) {
+			streamGroups(getCurrentConnection())
+		}),
+	}
+	listGroupsCmd.Flags().IntVarP(&pageSizeFV, pageSizeFlagKey, "", jh.DefaultPageSize, "number of groups to get from the hub at a time.")
+	listCmd.AddCommand(listGroupsCmd)
 
 	describeCmd.AddCommand(&cobra.Command{
 		Use:   "group <group-name>",
@@ -357,41 +763,61 @@ any other way. So, write it down if you intend to use it.`,
 		},
 	})
 
+	setGroupPropertyCmd := &cobra.Command{
+		Use:         "group-property [flags] <group-name> <key>=<value> ...",
+		Annotations: requires(jh.GroupProperties),
+		Aliases:     []string{"group-properties", "group-prop"},
+		Short:       "Set properties on a group.",
+		Long: `Sets the properties <key>=<value> ... on the Hub user group <group-name>, 
+keeping the group's other properties, and removes any properties named with --remove.
+Values that are JSON (numbers, true/false, lists, objects) are stored as such,
+anything else is stored as a string. Group properties need JupyterHub 3+.`,
+		Example: `  sponde set group-property ee201-spring2019 cpu_limit=2 mem_limit=4G
+  sponde set group-property ee201-spring2019 --remove cpu_limit`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			setGroupProperties(args[0], args[1:], removePropsFV)
+		},
+	}
+	setGroupPropertyCmd.Flags().StringSliceVarP(&removePropsFV, removeFlagKey, "", nil, "remove this property from the group.")
+	setCmd.AddCommand(setGroupPropertyCmd)
+
 	// Users in groups
-	addCmd.AddCommand(&cobra.Command{
-		Use:                   "user [flags] <user-id> <group-name> ...",
+	addUserCmd := &cobra.Command{
+		Use:                   "user [flags] <user-id> ... <group-name>",
 		DisableFlagsInUseLine: true,
 		Aliases:               []string{"users"},
-		Short:                 "Add user to a group.",
-		Long:                  "Add a user <user-id> to a group <group-name> or list of groups <group1> <group2> ....",
-		Example:               "  sponde add user david admin ee201-spring2019",
-		Args:                  cobra.MinimumNArgs(2),
+		Short:                 "Add users to a group.",
+		Long: `Add the users <user-id> ... to the group <group-name>, the last argument.
+With --group, add the one user <user-id> to each of the groups given with --group instead,
+up to --concurrency at a time.`,
+		Example: `  sponde add user david erin ee201-spring2019
+  sponde add user david --group admin --group ee201-spring2019`,
+		Args: groupMembersArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			ug := jh.UserGroup{
-				Name:      args[len(args)-1],
-				UserNames: args[:len(args)-1],
-			}
-			userGroup, resp, err := getCurrentConnection().AddUserToGroup(ug)
-			List(UserGroup(userGroup), resp, err)
+			changeGroupMembers(args, groupsFV, false)
 		},
-	})
+	}
+	addUserCmd.Flags().StringSliceVarP(&groupsFV, groupFlagKey, "g", nil, "add the user to this group (may be repeated).")
+	addCmd.AddCommand(addUserCmd)
 
-	removeCmd.AddCommand(&cobra.Command{
-		Use:                   "user [flags] <group-name> <user-id> ...",
+	removeUserCmd := &cobra.Command{
+		Use:                   "user [flags] <user-id> ... <group-name>",
 		DisableFlagsInUseLine: true,
 		Aliases:               []string{"users"},
-		Short:                 "Remove a user or users from a group",
-		Long:                  "Remove a user or list of users from the Hub user group <group-name>.",
-		Args:                  cobra.MinimumNArgs(2),
+		Short:                 "Remove users from a group",
+		Long: `Remove the users <user-id> ... from the Hub user group <group-name>, the last argument.
+With --group, remove the one user <user-id> from each of the groups given with --group instead,
+up to --concurrency at a time.`,
+		Example: `  sponde remove user david erin ee201-spring2019
+  sponde remove user david --group admin --group ee201-spring2019`,
+		Args: groupMembersArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			ug := jh.UserGroup{
-				Name:      args[len(args)-1],
-				UserNames: args[:len(args)-1],
-			}
-			userGroup, resp, err := getCurrentConnection().RemoveUserFromGroup(ug)
-			List(UserGroup(userGroup), resp, err)
+			changeGroupMembers(args, groupsFV, true)
 		},
-	})
+	}
+	removeUserCmd.Flags().StringSliceVarP(&groupsFV, groupFlagKey, "g", nil, "remove the user from this group (may be repeated).")
+	removeCmd.AddCommand(removeUserCmd)
 
 	// Services
 	listCmd.AddCommand(&cobra.Command{
@@ -399,16 +825,68 @@ any other way. So, write it down if you intend to use it.`,
 		Short: "Services registered with the Hub.",
 		Long:  "Returns details of the services that the Hub supports.",
 		Run: func(cmd *cobra.Command, args []string) {
-			services, err := getCurrentConnection().GetServices()
-			if err == nil && len(services) > 0 {
-				listServices(services)
-			} else {
-				if err != nil {
-					cmdError(err)
-				} else {
-					fmt.Println("There were no services.")
+			services, resp, err := getCurrentConnection().GetServices()
+			List(Services(services), resp, err)
+		},
+	})
+
+	describeCmd.AddCommand(&cobra.Command{
+		Use:   "service <service-name>",
+		Short: "Details of a service",
+		Long:  "Returns the details of the Hub service <service-name>, including its info.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			service, resp, err := getCurrentConnection().GetService(args[0])
+			display := func() {
+				if err == nil {
+					Service(service).Describe()
 				}
 			}
+			DisplayF(display, resp, err)
+		},
+	})
+
+	createServiceCmd := &cobra.Command{
+		Use:         "service [flags] <service-name> [<key>=<value> ...]",
+		Annotations: requires(jh.ManagedServices),
+		Short:       "Add an externally managed service to the Hub.",
+		Long: `Adds the service <service-name> to the hub, with the info <key>=<value> ...
+The service runs outside of the hub at --url, and can log users in with OAuth
+as --oauth-client-id. Adding services needs JupyterHub 5.1+.
+
+NOTE: This will display the service's API token independently of the show-tokens command 
+or any settings. This is the only place where this token will be displayed and you cannot 
+get it back any other way. So, write it down if you intend to use it.`,
+		Example:               "  sponde create service grader --url http://grader:9000 --oauth-client-id service-grader course=ee201",
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			createService(args[0], args[1:])
+		},
+	}
+	createServiceCmd.Flags().StringVarP(&serviceURLFV, serviceURLFlagKey, "", "", "where the service is running (e.g. http://grader:9000).")
+	createServiceCmd.Flags().StringVarP(&serviceOAuthClientIDFV, oauthClientIDFlagKey, "", "", "the service's OAuth client id (must start with service-).")
+	createServiceCmd.Flags().StringVarP(&serviceOAuthRedirectURIFV, oauthRedirectURIFlagKey, "", "", "where the hub sends users back to after logging in to the service.")
+	createServiceCmd.Flags().BoolVarP(&adminFV, adminFlagKey, "", false, "make the service an admin.")
+	createServiceCmd.Flags().BoolVarP(&serviceNoDisplayFV, noDisplayFlagKey, "", false, "don't show the service on the hub's services menu.")
+	createCmd.AddCommand(createServiceCmd)
+
+	deleteCmd.AddCommand(&cobra.Command{
+		Use:         "service <service-name>",
+		Annotations: requires(jh.ManagedServices),
+		Short:       "Remove a service from the Hub.",
+		Long: `Removes the service <service-name> from the hub.
+Only services added with create service can be removed, those in the
+hub's configuration can't.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			resp, err := getCurrentConnection().DeleteService(args[0])
+			display := func() {
+				if err == nil {
+					fmt.Printf("%s %s\n", t.Title("Deleted service"), t.Highlight(args[0]))
+				}
+			}
+			DisplayF(display, resp, err)
 		},
 	})
 
@@ -505,10 +983,43 @@ func init() {
 
 }
 
-const showTokensOnceFlagKey = "show-tokens"
+const (
+	showTokensOnceFlagKey   = "show-tokens"
+	pageSizeFlagKey         = "page-size"
+	stateFlagKey            = "state"
+	adminFlagKey            = "admin"
+	fileFlagKey             = "file"
+	expiresInFlagKey        = "expires-in"
+	scopeFlagKey            = "scope"
+	roleFlagKey             = "role"
+	removeFlagKey           = "remove"
+	noWaitFlagKey           = "no-wait"
+	optionFlagKey           = "option"
+	optionsFileFlagKey      = "options-file"
+	profileFlagKey          = "profile"
+	serverFlagKey           = "server"
+	everyFlagKey            = "every"
+	serviceURLFlagKey       = "url"
+	oauthClientIDFlagKey    = "oauth-client-id"
+	oauthRedirectURIFlagKey = "oauth-redirect-uri"
+	noDisplayFlagKey        = "no-display"
+	proxyAPIURLFlagKey      = "url"
+	proxyAuthTokenFlagKey   = "auth-token"
+	noBrowserFlagKey        = "no-browser"
+	withUserFlagKey         = "with-user"
+	withGroupFlagKey        = "with-group"
+	allFlagKey              = "all"
+	groupFlagKey            = "group"
+)
 
 var showTokensOnceFlagV bool
 
+// Flag values for creating tokens.
+var (
+	expiresInFV       time.Duration
+	scopesFV, rolesFV []string
+)
+
 func initJupyterHubFlags() {
 
 	// This flag should only work on the single command ie. it's not durable across
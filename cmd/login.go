@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+
+	t "github.com/jdrivas/sponde/term"
+	"github.com/spf13/viper"
+)
+
+// loginTimeout is how long login waits for the user to finish in the browser.
+const loginTimeout = 5 * time.Minute
+
+// noBrowserFV stops login from trying to open a browser.
+var noBrowserFV bool
+
+// login logs in to the hub of the named connection (or the current connection if name is "")
+// with OAuth and keeps the new token in that connection. Connections that aren't
+// in the config file (e.g. ones changed by flags) just get the token displayed.
+func login(name string) {
+	conn := getCurrentConnection()
+	if name != "" {
+		named, ok := getConnection(name)
+		if !ok {
+			cmdError(fmt.Errorf("couldn't find connection \"%s\"", name))
+			return
+		}
+		named.Auth.UpdateAuth(authFromFlags())
+		named.Logger = hubLogger()
+		conn = named
+	}
+
+	ctx, cancel := context.WithTimeout(commandContext, loginTimeout)
+	defer cancel()
+	hub := conn.WithContext(ctx)
+
+	token, resp, err := hub.Login(openBrowser)
+	display := func() {
+		if err != nil {
+			return
+		}
+		file, saveErr := saveToken(conn.Name, token.Token)
+		switch {
+		case saveErr != nil:
+			cmdError(saveErr)
+		case file == "":
+			fmt.Printf("\n%s %s\n", t.Success("Logged in, new token:"), t.Title("%s", token.Token))
+			fmt.Printf("%s\n", t.Text("The connection isn't in a config file, so this is the only time the token will be displayed."))
+		default:
+			fmt.Printf("%s %s%s %s\n", t.Success("Logged in to"), t.Highlight("%s", conn.Name),
+				t.Text(", the token is saved in"), t.Text("%s", file))
+		}
+	}
+	DisplayF(display, resp, err)
+}
+
+// openBrowser asks the user to log in at authURL, and opens
+// it in their browser unless --no-browser was given.
+func openBrowser(authURL string) error {
+	fmt.Printf("%s\n%s\n\n", t.Title("Log in to the hub at:"), t.Text("%s", authURL))
+	if noBrowserFV {
+		return nil
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", authURL)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", authURL)
+	default:
+		cmd = exec.Command("xdg-open", authURL)
+	}
+	// If there's no browser, the URL has already been printed.
+	if err := cmd.Start(); err == nil {
+		go cmd.Wait()
+	}
+	return nil
+}
+
+// saveToken stores token as the token of the named connection in the config file,
+// and for the rest of an interactive session. It returns the config file
+// written to, or "" if the connection isn't in a config file.
+func saveToken(name, token string) (file string, err error) {
+	if _, ok := getConnection(name); !ok || viper.ConfigFileUsed() == "" {
+		return "", nil
+	}
+	key := fmt.Sprintf("%s.%s.%s", connectionsKey, name, tokenKey)
+
+	// Write through a viper of our own, so that flags
+	// and other settings don't end up in the config file.
+	config := viper.New()
+	config.SetConfigFile(viper.ConfigFileUsed())
+	if err = config.ReadInConfig(); err != nil {
+		return "", err
+	}
+	config.Set(key, token)
+	if err = config.WriteConfig(); err != nil {
+		return "", err
+	}
+
+	viper.Set(key, token)
+	if currentConnection != nil && currentConnection.Name == name {
+		currentConnection.Token = token
+	}
+	return config.ConfigFileUsed(), nil
+}
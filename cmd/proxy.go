@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"sort"
 
 	jh "github.com/jdrivas/sponde/jupyterhub"
 	t "github.com/jdrivas/sponde/term"
@@ -16,7 +18,13 @@ func (r Routes) List() {
 	if len(routes) > 0 {
 		w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
 		fmt.Fprintf(w, " %s\n", t.Title("Routespec\tTarget\tUser\tLast Activity"))
-		for _, ri := range routes {
+		specs := make([]string, 0, len(routes))
+		for spec := range routes {
+			specs = append(specs, spec)
+		}
+		sort.Strings(specs)
+		for _, spec := range specs {
+			ri := routes[spec]
 			user := "<empty>"
 			if ri.Data.Hub && ri.Data.User != "" {
 				user = fmt.Sprintf("Hub : %s", ri.Data.User)
@@ -34,3 +42,39 @@ func (r Routes) List() {
 	}
 
 }
+
+// Flag values for updating the proxy.
+var (
+	proxyAPIURLFV    string
+	proxyAuthTokenFV string
+)
+
+// changeProxy displays the proxy's routing table before and after
+// change, which is one of the sync or update calls.
+func changeProxy(action string, change func() (*http.Response, error)) {
+	conn := getCurrentConnection()
+
+	routes, resp, err := fresh(conn).GetProxy()
+	if err != nil {
+		Display(resp, err)
+		return
+	}
+	fmt.Printf("%s\n", t.Title("Before %s", action))
+	Routes(routes).List()
+	fmt.Println()
+
+	resp, err = change()
+	if err != nil {
+		Display(resp, err)
+		return
+	}
+
+	routes, resp, err = conn.GetProxy()
+	display := func() {
+		if err == nil {
+			fmt.Printf("%s\n", t.Title("After %s", action))
+			Routes(routes).List()
+		}
+	}
+	DisplayF(display, resp, err)
+}
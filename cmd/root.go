@@ -5,7 +5,9 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
+	jh "github.com/jdrivas/sponde/jupyterhub"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	// "github.com/spf13/pflag"
@@ -17,6 +19,7 @@ var (
 	listCmd, describeCmd, createCmd, deleteCmd       *cobra.Command
 	addCmd, updateCmd, removeCmd                     *cobra.Command
 	startCmd, stopCmd                                *cobra.Command
+	shareCmd, revokeCmd                              *cobra.Command
 )
 
 // This is pulled out specially, because for interactive
@@ -123,6 +126,22 @@ func buildRoot(mode runMode) {
 	}
 	rootCmd.AddCommand(stopCmd)
 
+	shareCmd = &cobra.Command{
+		Use:         "share",
+		Annotations: requires(jh.ServerSharing),
+		Short:       "Share a resource on the hub.",
+		Long:        "Give other users and groups access to a resource on the JupyterHub hub.",
+	}
+	rootCmd.AddCommand(shareCmd)
+
+	revokeCmd = &cobra.Command{
+		Use:         "revoke",
+		Annotations: requires(jh.ServerSharing),
+		Short:       "Revoke access to a resource on the hub.",
+		Long:        "Take back access to a resource on the JupyterHub hub from other users and groups.",
+	}
+	rootCmd.AddCommand(revokeCmd)
+
 	httpCmd = &cobra.Command{
 		Use:   "http",
 		Short: "Use HTTP verbs.",
@@ -154,6 +173,20 @@ const (
 	authRedirectFlagKey = "auth-redirect-url"
 	clientIDFlagKey     = "client-id"
 	clientSecretFlagKey = "client-secret"
+	caFileFlagKey       = "ca-file"
+	clientCertFlagKey   = "client-cert"
+	clientKeyFlagKey    = "client-key"
+	insecureFlagKey     = "insecure"
+	proxyURLFlagKey     = "proxy-url"
+	timeoutFlagKey      = "timeout"
+	retriesFlagKey      = "retries"
+	retryBackoffFlagKey = "retry-backoff"
+	retryMaxFlagKey     = "retry-max-backoff"
+	retryPostFlagKey    = "retry-post"
+	concurrencyFlagKey  = "concurrency"
+	offlineFlagKey      = "offline"
+	maxAgeFlagKey       = "max-age"
+	logFileFlagKey      = "log-file"
 	verboseFlagKey      = "verbose"
 	debugFlagKey        = "debug"
 )
@@ -161,8 +194,13 @@ const (
 var (
 	cfgFile, tokenFV, hubURLFV                         string
 	authClientIDFV, authClientSecretFV, authRedirectFV string
+	caFileFV, clientCertFV, clientKeyFV, proxyURLFV    string
+	insecureFV, retryPostFV, offlineFV                 bool
+	timeoutFV, retryBackoffFV, retryMaxFV, maxAgeFV    time.Duration
+	retriesFV, concurrencyFV                           int
 
 	verbose, debug bool
+	logFileFV      string
 )
 
 func init() {
@@ -202,12 +240,34 @@ func initFlags() {
 	// Connection paramaters
 	rootCmd.PersistentFlags().StringVarP(&tokenFV, tokenFlagKey, "t", "", "connect to the JupyterhHub with this authorization token.")
 	rootCmd.PersistentFlags().StringVarP(&hubURLFV, hubURLFlagKey, "u", "",
-		fmt.Sprintf("connect to the JupyterhHub at this URL. (default is %s)", defaultHubURL))
+		fmt.Sprintf("connect to the JupyterhHub at this URL, the hub's own or its API's (.../hub/api). (default is %s)", defaultHubURL))
 
 	//  Auth paramaters
-	rootCmd.PersistentFlags().StringVarP(&authRedirectFV, authRedirectFlagKey, "", "", "OAuth redirect url - only need for auth commands.")
-	rootCmd.PersistentFlags().StringVarP(&authClientIDFV, clientIDFlagKey, "", "", "OAuth client id - only need for auth commands.")
-	rootCmd.PersistentFlags().StringVarP(&authClientSecretFV, clientSecretFlagKey, "", "", "OAuth client secret - only need for auth commands.")
+	rootCmd.PersistentFlags().StringVarP(&authRedirectFV, authRedirectFlagKey, "", "",
+		fmt.Sprintf("OAuth redirect url - only need for login. (default is %s)", jh.DefaultRedirectURL))
+	rootCmd.PersistentFlags().StringVarP(&authClientIDFV, clientIDFlagKey, "", "", "OAuth client id - only need for login.")
+	rootCmd.PersistentFlags().StringVarP(&authClientSecretFV, clientSecretFlagKey, "", "", "OAuth client secret - only need for login.")
+
+	// Transport paramaters
+	rootCmd.PersistentFlags().StringVarP(&caFileFV, caFileFlagKey, "", "", "trust the CAs in this PEM file when connecting to the hub.")
+	rootCmd.PersistentFlags().StringVarP(&clientCertFV, clientCertFlagKey, "", "", "present this client certificate to the hub (needs --client-key).")
+	rootCmd.PersistentFlags().StringVarP(&clientKeyFV, clientKeyFlagKey, "", "", "key for the --client-cert certificate.")
+	rootCmd.PersistentFlags().BoolVarP(&insecureFV, insecureFlagKey, "", false, "don't verify the hub's TLS certificate. DANGEROUS.")
+	rootCmd.PersistentFlags().StringVarP(&proxyURLFV, proxyURLFlagKey, "", "", "connect to the hub through this HTTP proxy.")
+	rootCmd.PersistentFlags().DurationVarP(&timeoutFV, timeoutFlagKey, "", 0, "give up on a hub request after this long (e.g. 30s). (default is no timeout)")
+
+	// Retry paramaters
+	rootCmd.PersistentFlags().IntVarP(&retriesFV, retriesFlagKey, "", jh.DefaultRetryPolicy.MaxRetries, "retry failed hub requests this many times.")
+	rootCmd.PersistentFlags().DurationVarP(&retryBackoffFV, retryBackoffFlagKey, "", jh.DefaultRetryPolicy.InitialBackoff, "wait this long before the first retry, doubling for each one after.")
+	rootCmd.PersistentFlags().DurationVarP(&retryMaxFV, retryMaxFlagKey, "", jh.DefaultRetryPolicy.MaxBackoff, "never wait longer than this between retries.")
+	rootCmd.PersistentFlags().BoolVarP(&retryPostFV, retryPostFlagKey, "", false, "also retry POST and PATCH requests, which may not be safe to repeat.")
+
+	rootCmd.PersistentFlags().IntVarP(&concurrencyFV, concurrencyFlagKey, "", jh.DefaultConcurrency, "make up to this many hub requests at once for commands on many users, groups or servers.")
+
+	// Cache paramaters
+	rootCmd.PersistentFlags().BoolVarP(&offlineFV, offlineFlagKey, "", false, "don't ask the hub, only show what's in the cache from earlier requests.")
+	rootCmd.PersistentFlags().DurationVarP(&maxAgeFV, maxAgeFlagKey, "", 0,
+		fmt.Sprintf("use cached answers up to this old (e.g. 10m) rather than asking the hub. (default is %s for list users, list groups, proxy and info in interactive mode, otherwise 0)", interactiveMaxAge))
 
 	// To suport configuration files populating values, as well as flags, bind the variables to
 	// the viper instance.
@@ -218,6 +278,9 @@ func initFlags() {
 	rootCmd.PersistentFlags().BoolVarP(&debug, debugFlagKey, "d", false, "Describe details about what's happening.")
 	viper.BindPFlag(debugFlagKey, rootCmd.PersistentFlags().Lookup(debugFlagKey))
 
+	rootCmd.PersistentFlags().StringVarP(&logFileFV, logFileFlagKey, "", "", "Write request logs to this file. (default is stderr)")
+	viper.BindPFlag(logFileFlagKey, rootCmd.PersistentFlags().Lookup(logFileFlagKey))
+
 	// Now init the Juphterhub specific flags.
 	initJupyterHubFlags()
 }
@@ -274,6 +337,54 @@ func initConnectionWithFlags() {
 		conn.Token = tokenFV
 		update = true
 	}
+	if rootCmd.PersistentFlags().Lookup(caFileFlagKey).Changed {
+		conn.Transport.CAFile = caFileFV
+		update = true
+	}
+	if rootCmd.PersistentFlags().Lookup(clientCertFlagKey).Changed {
+		conn.Transport.CertFile = clientCertFV
+		update = true
+	}
+	if rootCmd.PersistentFlags().Lookup(clientKeyFlagKey).Changed {
+		conn.Transport.KeyFile = clientKeyFV
+		update = true
+	}
+	if rootCmd.PersistentFlags().Lookup(insecureFlagKey).Changed {
+		conn.Transport.InsecureSkipVerify = insecureFV
+		update = true
+	}
+	if rootCmd.PersistentFlags().Lookup(proxyURLFlagKey).Changed {
+		conn.Transport.ProxyURL = proxyURLFV
+		update = true
+	}
+	if rootCmd.PersistentFlags().Lookup(timeoutFlagKey).Changed {
+		conn.Transport.Timeout = timeoutFV
+		update = true
+	}
+	if rootCmd.PersistentFlags().Lookup(retriesFlagKey).Changed {
+		conn.Retry.MaxRetries = retriesFV
+		update = true
+	}
+	if rootCmd.PersistentFlags().Lookup(retryBackoffFlagKey).Changed {
+		conn.Retry.InitialBackoff = retryBackoffFV
+		update = true
+	}
+	if rootCmd.PersistentFlags().Lookup(retryMaxFlagKey).Changed {
+		conn.Retry.MaxBackoff = retryMaxFV
+		update = true
+	}
+	if rootCmd.PersistentFlags().Lookup(retryPostFlagKey).Changed {
+		conn.Retry.RetryNonIdempotent = retryPostFV
+		update = true
+	}
+	if rootCmd.PersistentFlags().Lookup(concurrencyFlagKey).Changed {
+		conn.Concurrency = concurrencyFV
+		update = true
+	}
+	if auth := authFromFlags(); auth != (jh.Auth{}) {
+		conn.Auth.UpdateAuth(auth)
+		update = true
+	}
 	if update {
 		updateCurrentConnection(conn)
 	}
@@ -285,3 +396,18 @@ func initConnectionWithFlags() {
 	}
 
 }
+
+// authFromFlags returns the OAuth settings given with flags,
+// leaving the others blank.
+func authFromFlags() (auth jh.Auth) {
+	if rootCmd.PersistentFlags().Lookup(clientIDFlagKey).Changed {
+		auth.ClientID = authClientIDFV
+	}
+	if rootCmd.PersistentFlags().Lookup(clientSecretFlagKey).Changed {
+		auth.ClientSecret = authClientSecretFV
+	}
+	if rootCmd.PersistentFlags().Lookup(authRedirectFlagKey).Changed {
+		auth.RedirectURL = authRedirectFV
+	}
+	return auth
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	jh "github.com/jdrivas/sponde/jupyterhub"
+	t "github.com/jdrivas/sponde/term"
+	"github.com/juju/ansiterm"
+)
+
+// Servers is a proxy for a users servers, keyed by server name.
+type Servers map[string]jh.Server
+
+// names returns the server names sorted.
+func (ss Servers) names() (names []string) {
+	for k := range ss {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List displays a line for each server, sorted by name.
+func (ss Servers) List() {
+	if len(ss) == 0 {
+		fmt.Printf("There were no servers.\n")
+		return
+	}
+	w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+	fmt.Fprintf(w, "%s\n", t.Title("Name\tPod\tReady\tPending\tStarted\tLast Activity\tUptime\tIdle"))
+	for _, sn := range ss.names() {
+		s := ss[sn]
+		fmt.Fprintf(w, "%s\n", t.Text("%s\t%s\t%t\t%s\t%s\t%s\t%s\t%s", checkForEmptyString(s.Name), s.State.PodName, s.Ready,
+			checkForEmptyString(s.Pending), s.Started, s.LastActivity, durationOrUnknown(s.Uptime()), durationOrUnknown(s.IdleFor())))
+	}
+	w.Flush()
+}
+
+// listOptions displays the spawner options of each server that has them.
+func (ss Servers) listOptions() {
+	for _, sn := range ss.names() {
+		s := ss[sn]
+		if len(s.UserOptions) > 0 {
+			fmt.Printf("\nOptions for server %s\n", checkForEmptyString(s.Name))
+			w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+			fmt.Fprintf(w, "%s\n", t.Title("Option\tValue"))
+			fprintProperties(w, s.UserOptions, "")
+			w.Flush()
+		}
+	}
+}
+
+// Server is a proxy for jh.Server
+type Server jh.Server
+
+// Describe displays all of the details of a server.
+func (s Server) Describe() {
+	server := jh.Server(s)
+	lines := [][2]string{
+		{"Name:", checkForEmptyString(server.Name)},
+		{"Ready:", fmt.Sprintf("%t", server.Ready)},
+		{"Pending:", checkForEmptyString(server.Pending)},
+		{"URL:", checkForEmptyString(server.URL)},
+		{"Progress URL:", checkForEmptyString(server.ProgressURL)},
+		{"Started:", checkForEmptyString(server.Started.String())},
+		{"Last Activity:", checkForEmptyString(server.LastActivity.String())},
+		{"Uptime:", durationOrUnknown(server.Uptime())},
+		{"Idle:", durationOrUnknown(server.IdleFor())},
+		{"Pod:", checkForEmptyString(server.State.PodName)},
+	}
+	w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+	for _, l := range lines {
+		fmt.Fprintf(w, "%s\t%s\n", t.Title(l[0]), t.Text("%s", l[1]))
+	}
+	w.Flush()
+	Servers{server.Name: server}.listOptions()
+}
+
+// listServers lists the servers of the user username.
+func listServers(username string) {
+	user, resp, err := getCurrentConnection().GetUser(username)
+	List(Servers(user.Servers), resp, err)
+}
+
+// noWaitFV skips watching a server's progress after starting it.
+var noWaitFV bool
+
+// Flag values for the spawner options to start a server with.
+var (
+	optionsFV                []string
+	optionsFileFV, profileFV string
+)
+
+// userOptions collects the spawner options from the flags: first those in
+// the --options-file, then each --option, and lastly the --profile.
+func userOptions() (options jh.UserOptions, err error) {
+	options = make(jh.UserOptions)
+	if optionsFileFV != "" {
+		b, err := ioutil.ReadFile(optionsFileFV)
+		if err != nil {
+			return options, err
+		}
+		if err = json.Unmarshal(b, &options); err != nil {
+			return options, fmt.Errorf("couldn't read options from \"%s\": %v", optionsFileFV, err)
+		}
+	}
+
+	kvs, err := parseKeyValues(optionsFV)
+	if err != nil {
+		return options, err
+	}
+	for k, v := range kvs {
+		options[k] = v
+	}
+
+	if profileFV != "" {
+		options["profile"] = profileFV
+	}
+	return options, nil
+}
+
+// watchServerStart displays the start of a server as it happens: first the
+// response to the start request and then, if the server is pending, each
+// progress update from the hub until it's ready or fails.
+// start requests the server, watch follows its progress.
+func watchServerStart(start func() (bool, *http.Response, error),
+	watch func(func(jh.ProgressEvent) error) (*http.Response, error)) {
+
+	started, resp, err := start()
+	DisplayF(displayServerStartedF(started, resp, err), resp, err)
+	if err != nil || started || noWaitFV {
+		return
+	}
+
+	var last jh.ProgressEvent
+	resp, err = watch(func(ev jh.ProgressEvent) error {
+		displayProgress(ev)
+		last = ev
+		return nil
+	})
+	display := func() {
+		if err == nil && last.Ready {
+			fmt.Printf("%s %s %s\n", t.Title("Server"), t.Success("ready"), t.Text("%s", last.URL))
+		}
+	}
+	DisplayF(display, resp, err)
+}
+
+// displayProgress prints a single progress update.
+func displayProgress(ev jh.ProgressEvent) {
+	percent := t.Text("%3d%%", ev.Progress)
+	switch {
+	case ev.Failed:
+		percent = t.Fail("%3d%%", ev.Progress)
+	case ev.Ready:
+		percent = t.Success("%3d%%", ev.Progress)
+	}
+	fmt.Printf("%s %s\n", percent, t.Text("%s", ev.Message))
+}
+
+// Flag values for reporting activity.
+var (
+	activityServersFV []string
+	activityEveryFV   time.Duration
+)
+
+// reportActivity tells the hub that username and servers are active now and,
+// if every is set, again after each every until the command is stopped.
+func reportActivity(username string, servers []string, every time.Duration) {
+	conn := getCurrentConnection()
+	report := func() {
+		resp, err := conn.ReportActivityNow(username, servers...)
+		display := func() {
+			if err == nil {
+				fmt.Printf("%s %s %s\n", t.Title("Reported activity for"), t.Highlight(username), t.Text(time.Now().Format(time.RFC3339)))
+			}
+		}
+		DisplayF(display, resp, err)
+	}
+
+	report()
+	if every <= 0 {
+		return
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			report()
+		case <-conn.Context().Done():
+			return
+		}
+	}
+}
@@ -2,9 +2,111 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
+
+	jh "github.com/jdrivas/sponde/jupyterhub"
+	t "github.com/jdrivas/sponde/term"
+	"github.com/juju/ansiterm"
 )
 
-// ListServices print services to stdout.
-func listServices(services interface{}) {
-	fmt.Printf("Service: %#v\n", services)
+// Services is a proxy for jh.Services
+type Services jh.Services
+
+// Service is a proxy for jh.Service
+type Service jh.Service
+
+// List displays a line for each service.
+func (services Services) List() {
+	if len(services) == 0 {
+		fmt.Printf("There were no services.\n")
+		return
+	}
+	w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+	fmt.Fprintf(w, "%s\n", t.Title("Name\tAdmin\tURL\tPrefix\tPID"))
+	for _, s := range services {
+		pid := "<none>"
+		if s.PID != 0 {
+			pid = fmt.Sprintf("%d", s.PID)
+		}
+		fmt.Fprintf(w, "%s\n", t.SubTitle("%s\t%t\t%s\t%s\t%s", s.Name, s.Admin, checkForEmptyString(s.URL), checkForEmptyString(s.Prefix), pid))
+	}
+	w.Flush()
+}
+
+// Describe displays all of the details of a service, including its info.
+func (s Service) Describe() {
+	service := jh.Service(s)
+	pid := "<none>"
+	if service.PID != 0 {
+		pid = fmt.Sprintf("%d", service.PID)
+	}
+	lines := [][2]string{
+		{"Name:", service.Name},
+		{"Kind:", checkForEmptyString(service.Kind)},
+		{"Admin:", fmt.Sprintf("%t", service.Admin)},
+		{"URL:", checkForEmptyString(service.URL)},
+		{"Prefix:", checkForEmptyString(service.Prefix)},
+		{"PID:", pid},
+		{"Command:", checkForEmptyString(strings.Join(service.Command, " "))},
+		{"Roles:", joinOrEmpty(service.Roles)},
+		{"Display:", fmt.Sprintf("%t", service.Display)},
+		{"OAuth Client:", checkForEmptyString(service.OAuthClientID)},
+	}
+	w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+	for _, l := range lines {
+		fmt.Fprintf(w, "%s\t%s\n", t.Title(l[0]), t.Text("%s", l[1]))
+	}
+	w.Flush()
+
+	fmt.Println()
+	if len(service.Info) == 0 {
+		fmt.Printf("No Info\n")
+	} else {
+		fmt.Printf("Info\n")
+		w = ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+		fmt.Fprintf(w, "%s\n", t.Title("Key\tValue"))
+		fprintProperties(w, service.Info, "")
+		w.Flush()
+	}
+}
+
+// Flag values for creating services.
+var (
+	serviceURLFV              string
+	serviceOAuthClientIDFV    string
+	serviceOAuthRedirectURIFV string
+	serviceNoDisplayFV        bool
+)
+
+// createService adds the service name to the hub with the info in keyValues
+// and displays it, along with its new API token.
+func createService(name string, keyValues []string) {
+	info, err := parseKeyValues(keyValues)
+	if err != nil {
+		cmdError(err)
+		return
+	}
+	request := jh.ServiceRequest{
+		URL:              serviceURLFV,
+		Admin:            adminFV,
+		OAuthClientID:    serviceOAuthClientIDFV,
+		OAuthRedirectURI: serviceOAuthRedirectURIFV,
+		Info:             info,
+	}
+	if serviceNoDisplayFV {
+		display := false
+		request.Display = &display
+	}
+
+	service, resp, err := getCurrentConnection().CreateService(name, request)
+	display := func() {
+		if err == nil {
+			if service.APIToken != "" {
+				fmt.Printf("\n%s %s\n\n", t.Success("New service token:"), t.Title("%s", service.APIToken))
+			}
+			Service(service).Describe()
+		}
+	}
+	DisplayF(display, resp, err)
 }
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	jh "github.com/jdrivas/sponde/jupyterhub"
+	t "github.com/jdrivas/sponde/term"
+	"github.com/juju/ansiterm"
+)
+
+// Shares is a proxy for jh.Shares
+type Shares jh.Shares
+
+// ShareCodes is a proxy for jh.ShareCodes
+type ShareCodes jh.ShareCodes
+
+// serverSpec is <owner>/<server-name> or just <owner> for the default server.
+func serverSpec(owner, servername string) string {
+	if servername == "" {
+		return owner
+	}
+	return fmt.Sprintf("%s/%s", owner, servername)
+}
+
+// parseServerSpec splits <owner>[/<server-name>] into its parts.
+func parseServerSpec(spec string) (owner, servername string) {
+	i := strings.Index(spec, "/")
+	if i < 0 {
+		return spec, ""
+	}
+	return spec[:i], spec[i+1:]
+}
+
+// List displays a line for each share.
+func (shares Shares) List() {
+	if len(shares) == 0 {
+		fmt.Printf("There were no shares.\n")
+		return
+	}
+	w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+	fmt.Fprintf(w, "%s\n", t.Title("Server\tKind\tShared With\tScopes\tCreated\tAge"))
+	for _, s := range shares {
+		fmt.Fprintf(w, "%s\n", t.Text("%s\t%s\t%s\t%s\t%s\t%s", serverSpec(s.Server.User.Name, s.Server.Name),
+			s.Kind, s.With(), joinOrEmpty(s.Scopes), checkForEmptyString(s.CreatedAt.String()), durationOrUnknown(s.Age())))
+	}
+	w.Flush()
+}
+
+// List displays a line for each share code.
+func (codes ShareCodes) List() {
+	if len(codes) == 0 {
+		fmt.Printf("There were no share codes.\n")
+		return
+	}
+	w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+	fmt.Fprintf(w, "%s\n", t.Title("ID\tServer\tCreated\tExpires\tExpires In\tExchanges\tLast Exchanged"))
+	for _, c := range codes {
+		expiresIn := "<never>"
+		if !c.ExpiresAt.IsZero() {
+			expiresIn = "expired"
+			if d := c.ExpiresIn(); d > 0 {
+				expiresIn = durationOrUnknown(d)
+			}
+		}
+		fmt.Fprintf(w, "%s\n", t.Text("%s\t%s\t%s\t%s\t%s\t%d\t%s", c.ID, serverSpec(c.Server.User.Name, c.Server.Name),
+			checkForEmptyString(c.CreatedAt.String()), checkForEmptyString(c.ExpiresAt.String()), expiresIn, c.ExchangeCount,
+			checkForEmptyString(c.LastExchangedAt.String())))
+	}
+	w.Flush()
+}
+
+// Flag values for sharing servers.
+var (
+	shareUsersFV, shareGroupsFV []string
+	allSharesFV                 bool
+)
+
+// shareRequests returns a share request for each of the users and groups
+// in the flags, or an error if there aren't any.
+func shareRequests() (requests []jh.ShareRequest, err error) {
+	for _, u := range shareUsersFV {
+		requests = append(requests, jh.ShareRequest{User: u, Scopes: scopesFV})
+	}
+	for _, g := range shareGroupsFV {
+		requests = append(requests, jh.ShareRequest{Group: g, Scopes: scopesFV})
+	}
+	if len(requests) == 0 {
+		err = fmt.Errorf("no one to share with, use --%s and/or --%s", withUserFlagKey, withGroupFlagKey)
+	}
+	return requests, err
+}
+
+// listShares lists the shares on all of owner's servers, or on the one in spec.
+func listShares(spec string) {
+	conn := getCurrentConnection()
+	var shares jh.Shares
+	var resp *http.Response
+	var err error
+	owner, servername := parseServerSpec(spec)
+	if strings.Contains(spec, "/") {
+		shares, resp, err = conn.GetServerShares(owner, servername)
+	} else {
+		shares, resp, err = conn.GetShares(owner)
+	}
+	List(Shares(shares), resp, err)
+}
+
+// changeShares shares the server in spec with, or revokes it from, each of the
+// users and groups in the flags, displaying each share as it's changed.
+func changeShares(spec string, revoke bool) {
+	owner, servername := parseServerSpec(spec)
+	conn := getCurrentConnection()
+
+	if revoke && allSharesFV {
+		resp, err := conn.RevokeAllShares(owner, servername)
+		display := func() {
+			if err == nil {
+				fmt.Printf("%s %s\n", t.Title("Revoked all shares of"), t.Highlight("%s", serverSpec(owner, servername)))
+			}
+		}
+		DisplayF(display, resp, err)
+		return
+	}
+
+	requests, err := shareRequests()
+	if err != nil {
+		cmdError(err)
+		return
+	}
+	var shares jh.Shares
+	for _, r := range requests {
+		var share jh.Share
+		var resp *http.Response
+		if revoke {
+			share, resp, err = conn.RevokeShare(owner, servername, r)
+		} else {
+			share, resp, err = conn.ShareServer(owner, servername, r)
+		}
+		if err != nil {
+			Display(resp, err)
+			continue
+		}
+		if revoke {
+			with := r.User
+			if with == "" {
+				with = r.Group
+			}
+			fmt.Printf("%s %s %s %s\n", t.Title("Revoked"), t.Highlight("%s", serverSpec(owner, servername)),
+				t.Title("from"), t.Highlight("%s", with))
+			// What's left, if anything.
+			if len(share.Scopes) == 0 {
+				continue
+			}
+		}
+		shares = append(shares, share)
+	}
+	if len(shares) > 0 {
+		Shares(shares).List()
+	}
+}
+
+// createShareCode creates a share code for the server in spec,
+// and displays the URL to accept it at.
+func createShareCode(spec string) {
+	owner, servername := parseServerSpec(spec)
+	conn := getCurrentConnection()
+	code, resp, err := conn.CreateShareCode(owner, servername, int(expiresInFV.Seconds()))
+	display := func() {
+		if err != nil {
+			return
+		}
+		acceptURL := code.FullAcceptURL
+		if acceptURL == "" {
+			acceptURL = code.AcceptURL
+		}
+		fmt.Printf("\n%s %s\n\n", t.Success("Accept URL:"), t.Title("%s", acceptURL))
+		fmt.Printf("%s\n\n", t.Text("This is the only time the share code will be displayed."))
+		ShareCodes(jh.ShareCodes{code}).List()
+	}
+	DisplayF(display, resp, err)
+}
+
+// deleteShareCode revokes the share code id for the server in spec,
+// or all of them with --all.
+func deleteShareCode(spec, id string) {
+	owner, servername := parseServerSpec(spec)
+	conn := getCurrentConnection()
+	var resp *http.Response
+	var err error
+	switch {
+	case allSharesFV:
+		resp, err = conn.RevokeAllShareCodes(owner, servername)
+	case id != "":
+		resp, err = conn.RevokeShareCode(owner, servername, id)
+	default:
+		cmdError(fmt.Errorf("no share code to delete, give a share code id or --%s", allFlagKey))
+		return
+	}
+	display := func() {
+		if err == nil {
+			fmt.Printf("%s %s\n", t.Title("Revoked share codes of"), t.Highlight("%s", serverSpec(owner, servername)))
+		}
+	}
+	DisplayF(display, resp, err)
+}
@@ -31,22 +31,37 @@ func (a ByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
 func (ul UserList) List() {
 	users := jh.UserList(ul)
 	if len(users) > 0 {
-		w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
-		fmt.Fprintf(w, "%s\n", t.Title("Name\tAdmin\tGroups\tCreated\tPending\tServer\tLast"))
 		sort.Sort(ByName(users))
-		for _, u := range users {
-			serverURL := "<empty>"
-			if u.ServerURL != "" {
-				serverURL = u.ServerURL
-			}
-			fmt.Fprintf(w, "%s\n", t.SubTitle("%s\t%t\t%v\t%s\t%s\t%s\t%s", u.Name, u.Admin, u.Groups, u.Created, u.Pending, serverURL, u.LastActivity))
-		}
-		w.Flush()
+		UserList(users).listPage(newUsersTable(), true)
 	} else {
 		fmt.Printf("There were no users.\n")
 	}
 }
 
+// listPage prints the users in the order given, optionally
+// with a header, in the columns of table. Used to stream pages of users
+// as they arrive, with the same table for each page.
+func (ul UserList) listPage(table *pagedTable, header bool) {
+	titles := []string{"Name", "Admin", "Groups", "Created", "Pending", "Server", "Last", "Idle"}
+	var rows [][]string
+	for _, u := range ul {
+		serverURL := "<empty>"
+		if u.ServerURL != "" {
+			serverURL = u.ServerURL
+		}
+		rows = append(rows, []string{u.Name, fmt.Sprintf("%t", u.Admin), fmt.Sprintf("%v", u.Groups), u.Created.String(),
+			u.Pending, serverURL, u.LastActivity.String(), durationOrUnknown(u.IdleFor())})
+	}
+	if header {
+		table.fit(titles)
+		table.fit(rows...)
+		fmt.Printf("%s\n", t.Title("%s", table.line(titles)))
+	}
+	for _, row := range rows {
+		fmt.Printf("%s\n", t.SubTitle("%s", table.line(row)))
+	}
+}
+
 // Describe prints all of the infomration there is about each user in the list.
 // These are sorted by UserName and the servers are sorted by Name (this last
 // implemented with sort.Stings()
@@ -55,36 +70,19 @@ func (ul UserList) Describe() {
 	sort.Sort(ByName(users))
 	for _, u := range users {
 		w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
-		fmt.Fprintf(w, "Name\tKind\tAdmin\tServer\tCreated\tLast Activity\tPending\n")
+		fmt.Fprintf(w, "Name\tKind\tAdmin\tServer\tCreated\tLast Activity\tIdle\tPending\n")
 		pending := checkForEmptyString(u.Pending)
 		serverURL := checkForEmptyString(u.ServerURL)
-		fmt.Fprintf(w, "%s\t%s\n", t.Highlight("%s ", u.Name), t.Text("%s\t%t\t%s\t%s\t%s\t%s", u.Kind, u.Admin, serverURL, u.Created, u.LastActivity, pending))
+		fmt.Fprintf(w, "%s\t%s\n", t.Highlight("%s ", u.Name), t.Text("%s\t%t\t%s\t%s\t%s\t%s\t%s", u.Kind, u.Admin, serverURL, u.Created, u.LastActivity,
+			durationOrUnknown(u.IdleFor()), pending))
 		w.Flush()
 		fmt.Println()
 		if len(u.Servers) == 0 {
 			fmt.Printf("No Servers\n")
 		} else {
 			fmt.Printf("Servers\n")
-			w = ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
-			fmt.Fprintf(w, "%s\n", t.Title("Name\tPdd\tReady\tPending\tStarted\tLast Activity"))
-			var serverNames []string
-			for k := range u.Servers {
-				serverNames = append(serverNames, k)
-			}
-			sort.Strings(serverNames)
-			for _, sn := range serverNames {
-				s := u.Servers[sn]
-				name := "<empty>"
-				if s.Name != "" {
-					name = s.Name
-				}
-				pending := "<empty>"
-				if s.Pending != "" {
-					pending = u.Pending
-				}
-				fmt.Fprintf(w, "%s\n", t.Text("%s\t%s\t%t\t%s\t%s\t%s", name, s.State.PodName, s.Ready, pending, s.Started, s.LastActivity))
-			}
-			w.Flush()
+			Servers(u.Servers).List()
+			Servers(u.Servers).listOptions()
 		}
 		fmt.Println()
 	}
@@ -98,29 +96,74 @@ func describeUsers(u UserList, resp *http.Response, err error) {
 	Describe(u, resp, err)
 }
 
+// newUsersTable returns a table for listPage, with room for
+// the usual names, groups and times in case there's more than one page.
+func newUsersTable() *pagedTable {
+	return newPagedTable(12, 5, 12, len("2006-01-02T15:04:05.000000Z"), 7, 12, len("2006-01-02T15:04:05.000000Z"))
+}
+
+// usersTable holds the columns of the users being streamed by listUsersPage.
+var usersTable *pagedTable
+
+// And for streaming pages of users.
+func listUsersPage(u UserList, first bool) {
+	if first || usersTable == nil {
+		usersTable = newUsersTable()
+	}
+	u.listPage(usersTable, first)
+}
+func describeUsersPage(u UserList, first bool) {
+	u.Describe()
+}
+
+// pageSizeFV is the number of users (or groups) to ask the hub for at a time.
+var pageSizeFV int
+
+// stateFV limits users to those in a jh.UserState.
+var stateFV string
+
 // doUsers is a command handler that will print a list of all users on the hub
 // if no arguments are provided, or treat arguments as user names and print a list of users
 // found on the Hub with details, and the names of users not found on the hub.
-func doUsers(listFunc func(UserList, *http.Response, error)) func(*cobra.Command, []string) {
+// All users are displayed by pageFunc, a page at a time as they come in from the hub.
+func doUsers(listFunc func(UserList, *http.Response, error), pageFunc func(UserList, bool)) func(*cobra.Command, []string) {
 	return func(cmd *cobra.Command, args []string) {
 
-		var users jh.UserList
-		var badNames []string
-		var resp *http.Response
-		var err error
-
 		var conn = getCurrentConnection()
 
-		if len(args) > 0 {
-			users, badNames, resp, err = conn.GetUsers(args)
-		} else {
-			users, resp, err = conn.GetAllUsers()
+		state, err := jh.ParseUserState(stateFV)
+		if err != nil {
+			cmdError(err)
+			return
+		}
+
+		if len(args) == 0 {
+			streamUsers(conn, state, pageFunc)
+			return
+		}
+
+		results := conn.GetUsers(args)
+		users := results.Users()
+		if state != jh.AnyState {
+			var matched jh.UserList
+			for _, u := range users {
+				if state.Matches(u) {
+					matched = append(matched, u)
+				}
+			}
+			users = matched
 		}
 
 		// Display users
-		listFunc(UserList(users), resp, err)
+		var resps []*http.Response
+		for _, r := range results {
+			resps = append(resps, r.Resp)
+		}
+		cacheBanner(resps...)
+		listFunc(UserList(users), nil, nil)
 
 		// Print an extra line if you have both
+		badNames := results.NotFound()
 		if len(users) > 0 && len(badNames) > 0 {
 			fmt.Println("")
 		}
@@ -132,6 +175,103 @@ func doUsers(listFunc func(UserList, *http.Response, error)) func(*cobra.Command
 				fmt.Printf("%s\n", n)
 			}
 		}
+
+		// And the names that couldn't be looked up at all.
+		if failed := results.Failed(); len(failed) > 0 {
+			fmt.Println("")
+			listBatch("User", failed, func(jh.BatchResult) string { return "" })
+		}
+	}
+}
+
+// streamUsers displays all of the users on the hub in state, pageSizeFV at a time.
+func streamUsers(conn Connection, state jh.UserState, pageFunc func(UserList, bool)) {
+	count := 0
+	resp, err := conn.EachUsersPageByState(state, pageSizeFV, func(users jh.UserList, p jh.Pagination) error {
+		pageCacheBanner(p)
+		if len(users) > 0 {
+			pageFunc(UserList(users), count == 0)
+		}
+		count += len(users)
+		return nil
+	})
+	display := func() {
+		if count == 0 && err == nil {
+			if state == jh.AnyState {
+				fmt.Printf("There were no users.\n")
+			} else {
+				fmt.Printf("There were no %s users.\n", state)
+			}
+		}
+	}
+	DisplayF(display, resp, err)
+}
+
+// Flag values for creating and deleting users.
+var (
+	adminFV     bool
+	namesFileFV string
+)
+
+// createUsers creates the named users on the hub, and reports
+// on any that were already there.
+func createUsers(names []string, admin bool) {
+	if len(names) == 0 {
+		cmdError(errNoNames)
+		return
+	}
+	conn := getCurrentConnection()
+
+	var users jh.UserList
+	var resp *http.Response
+	var err error
+	if len(names) == 1 {
+		var user jh.User
+		user, resp, err = conn.CreateUser(names[0], admin)
+		if err == nil {
+			users = jh.UserList{user}
+		}
+	} else {
+		users, resp, err = conn.CreateUsers(names, admin)
+	}
+
+	display := func() {
+		if err != nil {
+			return
+		}
+		UserList(users).List()
+
+		created := make(map[string]bool)
+		for _, u := range users {
+			created[u.Name] = true
+		}
+		var existing []string
+		for _, n := range names {
+			if !created[n] {
+				existing = append(existing, n)
+			}
+		}
+		if len(existing) > 0 {
+			fmt.Printf("\nThere were %d user names already on the Hub:\n", len(existing))
+			for _, n := range existing {
+				fmt.Printf("%s\n", n)
+			}
+		}
+	}
+	DisplayF(display, resp, err)
+}
+
+// deleteUsers deletes each of the named users from the hub.
+func deleteUsers(names []string) {
+	conn := getCurrentConnection()
+	for _, name := range names {
+		resp, err := conn.DeleteUser(name)
+		display := func() {
+			if err == nil {
+				fmt.Printf("%s %s\n", t.Title("Deleted user"), t.Highlight(name))
+			}
+		}
+		DisplayF(display, resp, err)
 	}
 }
 
@@ -155,14 +295,14 @@ func (ts Tokens) List() {
 	tokens := jh.Tokens(ts)
 	if len(tokens.APITokens) > 0 || len(tokens.OAuthTokens) > 0 {
 		w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
-		fmt.Fprintf(w, "%s\n", t.Title("ID\tKind\tCreated\tExpires\tLast Activity\tNote (OAuth client)"))
+		fmt.Fprintf(w, "%s\n", t.Title("ID\tKind\tCreated\tExpires\tLast Activity\tScopes\tRoles\tNote (OAuth client)"))
 		for _, tk := range tokens.APITokens {
-			tk.Expires = checkForEmptyString(tk.Expires)
-			fmt.Fprintf(w, "%s\n", t.Text("%s\t%s\t%s\t%s\t%s\t%s", tk.ID, tk.Kind, tk.Created, tk.Expires, tk.LastActivity, tk.Note))
+			fmt.Fprintf(w, "%s\n", t.Text("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s", tk.ID, tk.Kind, tk.Created, checkForEmptyString(tk.Expires.String()), tk.LastActivity,
+				joinOrEmpty(tk.Scopes), joinOrEmpty(tk.Roles), tk.Note))
 		}
 		for _, tk := range tokens.OAuthTokens {
-			tk.Expires = checkForEmptyString(tk.Expires)
-			fmt.Fprintf(w, "%s\n", t.Text("%s\t%s\t%s\t%s\t%s\t%s", tk.ID, tk.Kind, tk.Created, tk.Expires, tk.LastActivity, tk.OAuthClient))
+			fmt.Fprintf(w, "%s\n", t.Text("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s", tk.ID, tk.Kind, tk.Created, checkForEmptyString(tk.Expires.String()), tk.LastActivity,
+				joinOrEmpty(tk.Scopes), joinOrEmpty(tk.Roles), tk.OAuthClient))
 		}
 		w.Flush()
 	} else {
@@ -178,7 +318,15 @@ func (tk APIToken) Describe() {
 	token := jh.APIToken(tk)
 	w := ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
 	fmt.Fprintf(w, "%s\n", t.Title("ID\tKind\tCreated\tExpires\tLast Activity\tNote (OAuth client)"))
-	token.Expires = checkForEmptyString(token.Expires)
-	fmt.Fprintf(w, "%s\n", t.Text("%s\t%s\t%s\t%s\t%s\t%s", token.ID, token.Kind, token.Created, token.Expires, token.LastActivity, token.Note))
+	fmt.Fprintf(w, "%s\n", t.Text("%s\t%s\t%s\t%s\t%s\t%s", token.ID, token.Kind, token.Created, checkForEmptyString(token.Expires.String()),
+		token.LastActivity, token.Note))
 	w.Flush()
+
+	if len(token.Scopes) > 0 || len(token.Roles) > 0 {
+		fmt.Println()
+		w = ansiterm.NewTabWriter(os.Stdout, 4, 4, 3, ' ', 0)
+		fmt.Fprintf(w, "%s\t%s\n", t.Title("Scopes:"), t.Text(joinOrEmpty(token.Scopes)))
+		fmt.Fprintf(w, "%s\t%s\n", t.Title("Roles:"), t.Text(joinOrEmpty(token.Roles)))
+		w.Flush()
+	}
 }
@@ -1,9 +1,23 @@
 package cmd
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	jh "github.com/jdrivas/sponde/jupyterhub"
+	t "github.com/jdrivas/sponde/term"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func checkForEmptyString(s string) (r string) {
@@ -14,6 +28,163 @@ func checkForEmptyString(s string) (r string) {
 	return r
 }
 
+// durationOrUnknown is d, briefly, like 3d4h or 5m10s;
+// or "<unknown>" if d is 0, i.e. the hub didn't say.
+func durationOrUnknown(d time.Duration) string {
+	if d <= 0 {
+		return "<unknown>"
+	}
+	d = d.Round(time.Second)
+	days, hours := d/(24*time.Hour), (d%(24*time.Hour))/time.Hour
+	mins, secs := (d%time.Hour)/time.Minute, (d%time.Minute)/time.Second
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, mins)
+	case mins > 0:
+		return fmt.Sprintf("%dm%ds", mins, secs)
+	}
+	return fmt.Sprintf("%ds", secs)
+}
+
+// pagedTable lines up a table that's printed a page at a time. The columns
+// start at minimum widths, are widened to fit the header and the first page,
+// and are kept at those widths for the pages after, so that the whole stream
+// reads as one table. A later cell that doesn't fit pushes the rest of its
+// line over, but only that line.
+type pagedTable struct {
+	widths []int
+}
+
+// newPagedTable returns a table with columns at least minWidths wide.
+func newPagedTable(minWidths ...int) *pagedTable {
+	return &pagedTable{widths: minWidths}
+}
+
+// fit widens the columns to fit the cells of rows.
+func (pt *pagedTable) fit(rows ...[]string) {
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(pt.widths) {
+				pt.widths = append(pt.widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > pt.widths[i] {
+				pt.widths[i] = n
+			}
+		}
+	}
+}
+
+// line pads the cells out to the column widths, with the same
+// 3 spaces between columns that the tab writers use.
+func (pt *pagedTable) line(cells []string) string {
+	var b strings.Builder
+	for i, cell := range cells {
+		if i == len(cells)-1 {
+			b.WriteString(cell)
+			break
+		}
+		width := 0
+		if i < len(pt.widths) {
+			width = pt.widths[i]
+		}
+		pad := width - utf8.RuneCountInString(cell)
+		if pad < 0 {
+			pad = 0
+		}
+		b.WriteString(cell)
+		b.WriteString(strings.Repeat(" ", pad+3))
+	}
+	return b.String()
+}
+
+// readNames returns names followed by the names read from the file fileName,
+// or from stdin if fileName is "-". Names in the file are separated by
+// white space and anything after a # on a line is ignored.
+// It's an error if that leaves no names at all, e.g. an empty file.
+func readNames(names []string, fileName string) ([]string, error) {
+	if fileName == "" {
+		return names, nil
+	}
+
+	var r io.Reader = os.Stdin
+	if fileName != "-" {
+		f, err := os.Open(fileName)
+		if err != nil {
+			return names, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		names = append(names, strings.Fields(line)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return names, err
+	}
+	if len(names) == 0 {
+		return names, errNoNames
+	}
+	return names, nil
+}
+
+// errNoNames is returned when neither the arguments nor the file name anyone.
+var errNoNames = errors.New("no user-ids given")
+
+// joinOrEmpty joins the strings with commas, or returns "<empty>" if there are none.
+func joinOrEmpty(ss []string) string {
+	return checkForEmptyString(strings.Join(ss, ", "))
+}
+
+// parseKeyValues turns key=value arguments into a map. Values that are valid
+// JSON (numbers, true/false, lists, objects, quoted strings) are decoded,
+// anything else is taken as a plain string.
+func parseKeyValues(args []string) (map[string]interface{}, error) {
+	kvs := make(map[string]interface{})
+	for _, arg := range args {
+		i := strings.Index(arg, "=")
+		if i <= 0 {
+			return kvs, fmt.Errorf("expected key=value but got \"%s\"", arg)
+		}
+		key, value := arg[:i], arg[i+1:]
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			v = value
+		}
+		kvs[key] = v
+	}
+	return kvs, nil
+}
+
+// fprintProperties writes the key-value pairs of props to w as a tab separated table,
+// sorted by key. Nested objects are written beneath their key, indented.
+func fprintProperties(w io.Writer, props map[string]interface{}, indent string) {
+	var keys []string
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch v := props[k].(type) {
+		case map[string]interface{}:
+			fmt.Fprintf(w, "%s\n", t.Text("%s%s\t", indent, k))
+			fprintProperties(w, v, indent+"  ")
+		case string:
+			fmt.Fprintf(w, "%s\n", t.Text("%s%s\t%s", indent, k, v))
+		default:
+			b, _ := json.Marshal(v)
+			fmt.Fprintf(w, "%s\n", t.Text("%s%s\t%s", indent, k, string(b)))
+		}
+	}
+}
+
 var trueValues = []string{"true", "True", "yes", "Yes"}
 var falseValues = []string{"false", "False", "no", "No"}
 var allValues = strings.Join(append(trueValues, falseValues...), ", ")
@@ -56,3 +227,138 @@ func ToggleVerbose() bool {
 	viper.Set(verboseFlagKey, viper.GetBool(verboseFlagKey))
 	return Verbose()
 }
+
+// The log file stays open across commands in interactive mode.
+var (
+	logFileName string
+	logFile     *os.File
+)
+
+// hubLogger returns a logger for hub requests at the level
+// set by debug and verbose, writing to the log file if there is one
+// and otherwise to stderr.
+func hubLogger() jh.Logger {
+	level := jh.LogWarn
+	switch {
+	case Debug():
+		level = jh.LogDebug
+	case Verbose():
+		level = jh.LogInfo
+	}
+	out := logOutput()
+	// The hub package writes its warnings to stderr too, only say them once.
+	jh.Warnings = os.Stderr
+	if out == os.Stderr {
+		jh.Warnings = nil
+	}
+	return jh.NewLogger(out, level)
+}
+
+func logOutput() io.Writer {
+	name := viper.GetString(logFileFlagKey)
+	if name == "" {
+		return os.Stderr
+	}
+	if name != logFileName {
+		f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			cmdError(fmt.Errorf("couldn't open log file, logging to stderr: %v", err))
+			return os.Stderr
+		}
+		if logFile != nil {
+			logFile.Close()
+		}
+		logFile, logFileName = f, name
+	}
+	return logFile
+}
+
+// interactiveMaxAge is how old a cached answer can be for a repeatable
+// command in interactive mode, when --max-age isn't given, so that
+// repeating it is quick.
+const interactiveMaxAge = 30 * time.Second
+
+// interactiveSession is true while running the interactive command loop.
+var interactiveSession bool
+
+// repeating is true while running a repeatable command.
+var repeating bool
+
+// repeatable marks run as a listing that's likely to be repeated, which may
+// use cached answers up to interactiveMaxAge old in interactive mode.
+func repeatable(run func(cmd *cobra.Command, args []string)) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		repeating = true
+		defer func() { repeating = false }()
+		run(cmd, args)
+	}
+}
+
+// maxAge is how old a cached answer can be and still be used instead of asking the hub.
+func maxAge() time.Duration {
+	if rootCmd.PersistentFlags().Lookup(maxAgeFlagKey).Changed || !interactiveSession || !repeating {
+		return maxAgeFV
+	}
+	return interactiveMaxAge
+}
+
+// fresh returns a copy of conn that always asks the hub, for reads
+// that a change is going to be based on.
+func fresh(conn Connection) Connection {
+	c := conn.copy()
+	c.MaxAge = 0
+	return c
+}
+
+// responseCache returns the cache for the hub at hubURL, a directory named for the hub
+// in the user's cache directory (e.g. ~/.cache/sponde/hub.example.com), or nil if
+// there's no cache directory.
+func responseCache(hubURL string) jh.ResponseCache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(hubURL, "https://"), "http://")
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSuffix(name, "/"))
+	return jh.NewDiskCache(filepath.Join(dir, "sponde", name))
+}
+
+// cacheBanner says how old the oldest of the responses is, if any came from the cache.
+func cacheBanner(resps ...*http.Response) {
+	var oldest time.Time
+	for _, resp := range resps {
+		if at, ok := jh.CachedAt(resp); ok && (oldest.IsZero() || at.Before(oldest)) {
+			oldest = at
+		}
+	}
+	showCacheBanner(oldest)
+}
+
+// pageCacheBanner shows the cache banner ahead of the rows of a streamed listing,
+// where it's hard to miss, if its first page came from the cache.
+// render won't show it again after the rows.
+func pageCacheBanner(p jh.Pagination) {
+	if p.Offset == 0 && !p.CachedAt.IsZero() {
+		showCacheBanner(p.CachedAt)
+		cacheBannerShown = true
+	}
+}
+
+// cacheBannerShown is set once a listing has shown the cache banner ahead of its rows.
+var cacheBannerShown bool
+
+func showCacheBanner(oldest time.Time) {
+	if oldest.IsZero() {
+		return
+	}
+	source := "From the cache"
+	if offlineFV {
+		source = "Offline, from the cache"
+	}
+	fmt.Printf("%s\n", t.Warn("%s: %s old (%s).", source, durationOrUnknown(time.Since(oldest)), oldest.Local().Format(time.RFC1123)))
+}
@@ -1,15 +1,73 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	jh "github.com/jdrivas/sponde/jupyterhub"
 	t "github.com/jdrivas/sponde/term"
+	"github.com/spf13/cobra"
 )
 
 type Version jh.Version
 
 func (v Version) List() {
 	version := jh.Version(v)
-	fmt.Printf("%s %s\n", t.Title("JupyterHub Version:"), t.Text(version.Version))
+	fmt.Printf("%s %s\n", t.Title("JupyterHub Version:"), t.Text("%s", version.Version))
+}
+
+// requiresAnnotation is the command annotation holding the
+// JupyterHub version the command needs.
+const requiresAnnotation = "requires-jupyterhub"
+
+// requires returns the annotations for a command that needs a hub with feature f.
+func requires(f jh.Feature) map[string]string {
+	return map[string]string{requiresAnnotation: f.Since.String()}
+}
+
+// probedVersion is the hub version hideUnsupported last found, and the
+// connection it was found for, so that the hub is only asked again when
+// the connection changes. Failures aren't kept, the hub may be back next time.
+var probedVersion struct {
+	hubURL, token string
+	version       jh.HubVersion
+}
+
+// hideUnsupported hides the commands under root that need a newer hub than
+// the current connection's, and has them fail, without asking the hub, if
+// they're run anyway. If the hub's version can't be found, say because
+// the hub is down, nothing is hidden and it's asked again next time.
+func hideUnsupported(root *cobra.Command) {
+	conn := *getCurrentConnection().Connection
+	if probedVersion.hubURL != conn.HubURL || probedVersion.token != conn.Token {
+		// Don't hold up the prompt, or clutter the output, finding the version.
+		conn.Retry = jh.RetryPolicy{}
+		conn.Logger = jh.NewLogger(logOutput(), jh.LogWarn)
+		ctx, cancel := context.WithTimeout(conn.Context(), 2*time.Second)
+		version, err := conn.WithContext(ctx).HubVersion()
+		cancel()
+		if err != nil {
+			return
+		}
+		probedVersion.hubURL, probedVersion.token, probedVersion.version = conn.HubURL, conn.Token, version
+	}
+	version := probedVersion.version
+
+	var hide func(cmd *cobra.Command)
+	hide = func(cmd *cobra.Command) {
+		if since, ok := cmd.Annotations[requiresAnnotation]; ok {
+			if min, err := jh.ParseVersion(since); err == nil && !version.AtLeast(min) {
+				cmd.Hidden = true
+				cmd.RunE = nil
+				cmd.Run = func(cmd *cobra.Command, args []string) {
+					cmdError(&jh.VersionError{Feature: jh.Feature{Name: cmd.CommandPath(), Since: min}, Version: version})
+				}
+			}
+		}
+		for _, c := range cmd.Commands() {
+			hide(c)
+		}
+	}
+	hide(root)
 }
@@ -0,0 +1,39 @@
+package jupyterhub
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Activity reports when a user, and any of their servers, were last active.
+// The hub uses it to keep servers from being culled as idle.
+type Activity struct {
+	LastActivity time.Time                 `json:"last_activity"`
+	Servers      map[string]ServerActivity `json:"servers,omitempty"` // Keyed by server name, "" is the default server.
+}
+
+// ServerActivity is the activity of a single server.
+type ServerActivity struct {
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// ReportActivity tells the hub about username's activity.
+func (conn Connection) ReportActivity(username string, activity Activity) (resp *http.Response, err error) {
+	return conn.Post(fmt.Sprintf("/users/%s/activity", username), activity, nil)
+}
+
+// ReportActivityNow tells the hub that username, and each of the servers
+// named in servernames ("" for the default server), are active now.
+func (conn Connection) ReportActivityNow(username string, servernames ...string) (resp *http.Response, err error) {
+	// The hub's Python doesn't do nanoseconds.
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	activity := Activity{LastActivity: now}
+	if len(servernames) > 0 {
+		activity.Servers = make(map[string]ServerActivity)
+		for _, sn := range servernames {
+			activity.Servers[sn] = ServerActivity{LastActivity: now}
+		}
+	}
+	return conn.ReportActivity(username, activity)
+}
@@ -1,15 +1,102 @@
 package jupyterhub
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 )
 
-// TODO: This needs to work for users and services!
+// Kinds of TokenOwner.
+const (
+	UserKind    = "user"
+	ServiceKind = "service"
+)
+
+// TokenOwner is the user or service that a token belongs to.
+// Kind says which one of User or Service is set.
+type TokenOwner struct {
+	Kind    string
+	User    *User
+	Service *Service
+}
+
+// UnmarshalJSON decodes the owner into a User or a Service, using the kind field.
+// Hubs older than 1.0 don't send the kind of a user.
+func (o *TokenOwner) UnmarshalJSON(b []byte) error {
+	var k struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(b, &k); err != nil {
+		return err
+	}
+	switch k.Kind {
+	case ServiceKind:
+		o.Kind, o.User, o.Service = ServiceKind, nil, &Service{}
+		return json.Unmarshal(b, o.Service)
+	case UserKind, "":
+		o.Kind, o.User, o.Service = UserKind, &User{}, nil
+		return json.Unmarshal(b, o.User)
+	}
+	return fmt.Errorf("unknown token owner kind %q", k.Kind)
+}
+
+// Name is the name of the user or service.
+func (o TokenOwner) Name() string {
+	switch {
+	case o.User != nil:
+		return o.User.Name
+	case o.Service != nil:
+		return o.Service.Name
+	}
+	return ""
+}
+
+// Admin is true if the user or service is an admin.
+func (o TokenOwner) Admin() bool {
+	switch {
+	case o.User != nil:
+		return o.User.Admin
+	case o.Service != nil:
+		return o.Service.Admin
+	}
+	return false
+}
+
+// Roles are the roles of the user or service.
+func (o TokenOwner) Roles() []string {
+	switch {
+	case o.User != nil:
+		return o.User.Roles
+	case o.Service != nil:
+		return o.Service.Roles
+	}
+	return nil
+}
+
+// Scopes are the expanded scopes of the token, these are only sent by Whoami.
+func (o TokenOwner) Scopes() []string {
+	switch {
+	case o.User != nil:
+		return o.User.Scopes
+	case o.Service != nil:
+		return o.Service.Scopes
+	}
+	return nil
+}
+
+// GetTokenOwner returns the user or service that token belongs to.
+// This API was deprecated in JupyterHub 2, use Whoami with a
+// connection that has the token for newer hubs.
+func (conn Connection) GetTokenOwner(token string) (owner TokenOwner, resp *http.Response, err error) {
+	resp, err = conn.Get("/authorizations/token/"+token, &owner)
+	return owner, resp, err
+}
 
-// GetTokenOwner will return the user assocaited wit the token.
-func (conn Connection) GetTokenOwner(token string) (u User, resp *http.Response, err error) {
-	resp, err = conn.Get("/authorizations/token/"+token, &u)
-	return u, resp, err
+// Whoami returns the user or service that owns the connection's token,
+// along with the scopes the token actually has (JupyterHub 2+).
+func (conn Connection) Whoami() (owner TokenOwner, resp *http.Response, err error) {
+	resp, err = conn.Get("/user", &owner)
+	return owner, resp, err
 }
 
 /* DPRECATED API, so we'll leave it out.
@@ -0,0 +1,93 @@
+package jupyterhub
+
+import (
+	"net/http"
+	"sync"
+)
+
+// DefaultConcurrency is how many requests of a batch are made at once
+// when the connection doesn't say.
+const DefaultConcurrency = 8
+
+// BatchResult is how one of the requests of a batch went.
+type BatchResult struct {
+	Name string // The user, group etc. the request was about.
+	Resp *http.Response
+	Err  error
+}
+
+// StatusCode is the HTTP status of the hub's answer, or 0 if there wasn't one.
+func (r BatchResult) StatusCode() int {
+	if r.Resp == nil {
+		return 0
+	}
+	return r.Resp.StatusCode
+}
+
+// BatchResults are the results of a batch, in the order the names were given.
+type BatchResults []BatchResult
+
+// Failed returns the results with errors.
+func (rs BatchResults) Failed() (failed BatchResults) {
+	for _, r := range rs {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// Err is the first of the results' errors, nil if all of them succeeded.
+func (rs BatchResults) Err() error {
+	for _, r := range rs {
+		if r.Err != nil {
+			return r.Err
+		}
+	}
+	return nil
+}
+
+// concurrency is the number of requests to make at once.
+func (conn Connection) concurrency() int {
+	if conn.Concurrency > 0 {
+		return conn.Concurrency
+	}
+	return DefaultConcurrency
+}
+
+// batch calls f for each of the n items of a batch, with at most
+// conn.concurrency() calls running at once, and returns when they have all finished.
+// f is given the item's index, so it can put its result in place without locking.
+// Every item is tried, a failure doesn't stop the others.
+func (conn Connection) batch(n int, f func(i int)) {
+	work := make(chan int)
+	var wg sync.WaitGroup
+	workers := conn.concurrency()
+	if workers > n {
+		workers = n
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+}
+
+// batchNames runs f on each of names, in a batch, and returns each of the results.
+func (conn Connection) batchNames(names []string, f func(name string) (*http.Response, error)) BatchResults {
+	results := make(BatchResults, len(names))
+	conn.batch(len(names), func(i int) {
+		resp, err := f(names[i])
+		results[i] = BatchResult{Name: names[i], Resp: resp, Err: err}
+	})
+	return results
+}
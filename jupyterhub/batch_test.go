@@ -0,0 +1,113 @@
+package jupyterhub
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jdrivas/sponde/jupyterhubtest"
+)
+
+func TestBatchConcurrency(t *testing.T) {
+	conn := Connection{Concurrency: 3}
+
+	var mu sync.Mutex
+	running, most := 0, 0
+	done := make([]bool, 20)
+	conn.batch(len(done), func(i int) {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		done[i] = true
+		mu.Unlock()
+	})
+
+	if most > 3 {
+		t.Errorf("%d ran at once, want at most 3", most)
+	}
+	for i, d := range done {
+		if !d {
+			t.Errorf("item %d wasn't done", i)
+		}
+	}
+}
+
+func TestGetUsersFailures(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.AddFault(jupyterhubtest.Fault{Method: http.MethodGet, Path: "/users/bob", Status: http.StatusInternalServerError})
+
+	results := conn.GetUsers([]string{"nobody", "bob", "alice"})
+	if len(results) != 3 {
+		t.Fatalf("GetUsers = %d results, want 3", len(results))
+	}
+	for i, name := range []string{"nobody", "bob", "alice"} {
+		if results[i].Name != name {
+			t.Errorf("result %d is for %s, want %s", i, results[i].Name, name)
+		}
+	}
+	if users := results.Users(); len(users) != 1 || users[0].Name != "alice" {
+		t.Errorf("Users = %v, want alice", users)
+	}
+	if missing := results.NotFound(); len(missing) != 1 || missing[0] != "nobody" {
+		t.Errorf("NotFound = %v, want nobody", missing)
+	}
+	failed := results.Failed()
+	if len(failed) != 1 || failed[0].Name != "bob" || failed[0].StatusCode() != http.StatusInternalServerError {
+		t.Errorf("Failed = %+v, want bob with a 500", failed)
+	}
+}
+
+func TestStartStopServers(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.StartServer("bob", "")
+
+	results := conn.StartServers([]string{"alice", "bob"}, nil)
+	if results[0].Err != nil || results[0].StatusCode() != http.StatusCreated {
+		t.Errorf("starting alice = %d, %v", results[0].StatusCode(), results[0].Err)
+	}
+	if !IsBadRequest(results[1].Err) {
+		t.Errorf("starting bob's running server: err = %v, want 400", results[1].Err)
+	}
+
+	results = conn.StopServers([]string{"alice", "bob"})
+	if err := results.Err(); err != nil {
+		t.Errorf("StopServers: %v", err)
+	}
+	for _, name := range []string{"alice", "bob"} {
+		if u, _, _ := conn.GetUser(name); len(u.Servers) != 0 {
+			t.Errorf("%s still has servers %v", name, u.Servers)
+		}
+	}
+}
+
+func TestUserToGroups(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.AddGroup("students")
+	hub.AddGroup("staff")
+
+	results := conn.AddUserToGroups("alice", []string{"students", "staff", "nothing"})
+	if results[0].Err != nil || results[1].Err != nil || !IsNotFound(results[2].Err) {
+		t.Errorf("AddUserToGroups = %v, %v, %v; want the last not found", results[0].Err, results[1].Err, results[2].Err)
+	}
+	u, _, _ := conn.GetUser("alice")
+	if len(u.Groups) != 2 {
+		t.Errorf("alice is in %v, want students and staff", u.Groups)
+	}
+
+	if err := conn.RemoveUserFromGroups("alice", []string{"students", "staff"}).Err(); err != nil {
+		t.Errorf("RemoveUserFromGroups: %v", err)
+	}
+	u, _, _ = conn.GetUser("alice")
+	if len(u.Groups) != 0 {
+		t.Errorf("alice is still in %v", u.Groups)
+	}
+}
@@ -0,0 +1,301 @@
+package jupyterhub
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseCache keeps the hub's successful answers to GET requests,
+// so they can be used again, say when the hub can't be reached.
+// The keys include the request's token, so one cache can be shared
+// by connections with different tokens.
+//
+// A connection with a Cache saves every successful GET response in it,
+// and answers from it instead of asking the hub when Offline is set or the
+// answer is younger than MaxAge. When any other kind of request succeeds,
+// the entries it may have made stale are invalidated.
+//
+// Entries are kept by the path of the request, below the API URL (with any
+// token redacted), as well as by key. Invalidating a path removes the entries
+// for it, for the paths under it and for the paths above it, but not the API's
+// root, e.g. invalidating /users/alice removes /users/alice/tokens and the /users
+// list, but not /users/bob or /groups.
+type ResponseCache interface {
+	Get(path, key string) (entry CacheEntry, ok bool)
+	Put(path, key string, entry CacheEntry) error
+	Invalidate(path string) error
+}
+
+// CacheEntry is a cached response.
+type CacheEntry struct {
+	Time       time.Time   `json:"time"` // When the hub sent the response.
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// ErrOffline is returned, without asking the hub, for requests that
+// can't be answered from the cache of an Offline connection.
+var ErrOffline = errors.New("offline")
+
+// CachedHeader is added to responses that come from the cache, with when
+// the hub sent them.
+const CachedHeader = "X-Sponde-Cached"
+
+// CachedAt is when the hub sent resp, if resp came from the cache.
+func CachedAt(resp *http.Response) (at time.Time, ok bool) {
+	if resp == nil {
+		return at, false
+	}
+	at, err := time.Parse(time.RFC3339Nano, resp.Header.Get(CachedHeader))
+	return at, err == nil
+}
+
+// cacheKey tells cached responses apart by URL, token and the kind
+// (e.g. paginated or not) of answer asked for.
+func cacheKey(req *http.Request) string {
+	return strings.Join([]string{req.Method, req.URL.String(), req.Header.Get("Accept"), req.Header.Get("Authorization")}, " ")
+}
+
+// cachePath is req's path below the hub's API URL, with any token redacted,
+// e.g. /users/alice or /authorizations/token/[REDACTED].
+func (conn Connection) cachePath(req *http.Request) string {
+	path := req.URL.Path
+	if api, err := url.Parse(conn.apiURL()); err == nil {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(api.Path, "/"))
+	}
+	return Redact(path)
+}
+
+// stalePaths are the paths to invalidate after a change to path: path itself,
+// and those it's linked to. Servers are routes on the proxy, and groups and
+// users list each other.
+func stalePaths(path string) []string {
+	paths := []string{path}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case parts[0] == "users" && len(parts) >= 3 && strings.HasPrefix(parts[2], "server"):
+		paths = append(paths, "/proxy")
+	case parts[0] == "users" && len(parts) <= 2:
+		paths = append(paths, "/groups")
+	case parts[0] == "groups" && len(parts) >= 3 && parts[2] == "users":
+		paths = append(paths, "/users")
+	}
+	return paths
+}
+
+// fromCache returns the cached response to req, if the connection
+// should use it rather than asking the hub.
+func (conn Connection) fromCache(req *http.Request) (resp *http.Response, ok bool) {
+	if conn.Cache == nil || req.Method != http.MethodGet || (!conn.Offline && conn.MaxAge <= 0) {
+		return resp, false
+	}
+	entry, ok := conn.Cache.Get(conn.cachePath(req), cacheKey(req))
+	if !ok || (!conn.Offline && time.Since(entry.Time) > conn.MaxAge) {
+		return resp, false
+	}
+
+	header := http.Header{}
+	for k, vs := range entry.Header {
+		header[k] = vs
+	}
+	header.Set(CachedHeader, entry.Time.Format(time.RFC3339Nano))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}, true
+}
+
+// toCache saves the hub's successful answer to a GET request.
+// The body is read, and replaced so that it can be read again.
+func (conn Connection) toCache(req *http.Request, resp *http.Response) {
+	if conn.Cache == nil || req.Method != http.MethodGet || resp.StatusCode >= 300 {
+		return
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	entry := CacheEntry{
+		Time:       time.Now(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}
+	if err := conn.Cache.Put(conn.cachePath(req), cacheKey(req), entry); err != nil {
+		conn.logf(LogInfo, "Couldn't cache %s: %v", req.URL, err)
+	}
+}
+
+// invalidateCache removes what may have gone stale from the cache,
+// after req changed the hub.
+func (conn Connection) invalidateCache(req *http.Request) {
+	if conn.Cache == nil || req == nil {
+		return
+	}
+	for _, path := range stalePaths(conn.cachePath(req)) {
+		if err := conn.Cache.Invalidate(path); err != nil {
+			conn.logf(LogInfo, "Couldn't invalidate %s in the cache: %v", path, err)
+		}
+	}
+}
+
+// DiskCacheLifetime is how long a disk cache keeps a response. Older ones
+// are removed the first time a process saves a response in the cache.
+var DiskCacheLifetime = 7 * 24 * time.Hour
+
+// NewDiskCache returns a ResponseCache that keeps each response in a file in dir.
+// The files are in directories for each part of the response's path, so that
+// a path can be invalidated without reading the rest of the cache, and are named
+// with hashes (of the key, and of the parts) so that tokens aren't written to disk.
+// The directory is created when the first response is saved.
+func NewDiskCache(dir string) ResponseCache {
+	return diskCache(dir)
+}
+
+type diskCache string
+
+// Disk caches that have had their old responses removed by this process.
+var (
+	sweptMu sync.Mutex
+	swept   = make(map[string]bool)
+)
+
+// dir is the directory for the entries of path, e.g. <cache>/<hash of users>/<hash of alice>.
+func (dc diskCache) dir(path string) string {
+	dir := string(dc)
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if part != "" {
+			dir = filepath.Join(dir, hash(part)[:16])
+		}
+	}
+	return dir
+}
+
+func (dc diskCache) file(path, key string) string {
+	return filepath.Join(dc.dir(path), hash(key)+".json")
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func (dc diskCache) Get(path, key string) (entry CacheEntry, ok bool) {
+	b, err := ioutil.ReadFile(dc.file(path, key))
+	if err != nil {
+		return entry, false
+	}
+	return entry, json.Unmarshal(b, &entry) == nil
+}
+
+// Put writes the entry to a temporary file and then renames it,
+// so that readers never see half of one.
+func (dc diskCache) Put(path, key string, entry CacheEntry) error {
+	dc.sweep()
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	dir := dc.dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), dc.file(path, key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Invalidate removes the directory of path, with the entries under it, and the
+// entries (but not the directories) of the paths above it, short of the root.
+// Removing the root's directory would empty the whole cache, so for the root
+// only its own entries are removed.
+func (dc diskCache) Invalidate(path string) error {
+	dir := dc.dir(path)
+	if dir == string(dc) {
+		return removeEntries(dir)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	for dir = filepath.Dir(dir); dir != string(dc); dir = filepath.Dir(dir) {
+		if err := removeEntries(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeEntries removes the entries in dir, leaving its sub-directories.
+func removeEntries(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// sweep removes the files older than DiskCacheLifetime, and the directories
+// that leaves empty, the first time it's called for the cache in this process.
+func (dc diskCache) sweep() {
+	sweptMu.Lock()
+	defer sweptMu.Unlock()
+	if swept[string(dc)] {
+		return
+	}
+	swept[string(dc)] = true
+
+	var dirs []string
+	filepath.Walk(string(dc), func(path string, info os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+		case info.IsDir():
+			dirs = append(dirs, path)
+		case time.Since(info.ModTime()) > DiskCacheLifetime:
+			os.Remove(path)
+		}
+		return nil
+	})
+	// Deepest first, so that emptied parents go too. The cache's own directory stays.
+	for i := len(dirs) - 1; i > 0; i-- {
+		os.Remove(dirs[i])
+	}
+}
@@ -0,0 +1,187 @@
+package jupyterhub
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheMaxAge(t *testing.T) {
+	hub, conn := newTestHub(t)
+	conn.Cache = NewDiskCache(t.TempDir())
+	conn.MaxAge = time.Minute
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := conn.GetUser("alice"); err != nil {
+			t.Fatalf("GetUser: %v", err)
+		}
+	}
+	if n := countRequests(hub, "GET /users/alice"); n != 1 {
+		t.Errorf("the hub was asked for alice %d times, want once", n)
+	}
+	_, resp, _ := conn.GetUser("alice")
+	if at, ok := CachedAt(resp); !ok || time.Since(at) > time.Minute {
+		t.Errorf("CachedAt = %v, %v; want a cached response", at, ok)
+	}
+
+	// Another token doesn't get the cached answer.
+	alice := conn
+	alice.Token = "alice-token"
+	if _, resp, err := alice.GetUser("alice"); err != nil || !notCached(resp) {
+		t.Errorf("GetUser with alice's token: cached %v, %v", !notCached(resp), err)
+	}
+
+	// A change that fails leaves the cache alone.
+	if _, _, err := conn.CreateUser("alice", false); err == nil {
+		t.Fatalf("CreateUser of an existing user didn't fail")
+	}
+	if _, resp, err := conn.GetUser("alice"); err != nil || notCached(resp) {
+		t.Errorf("GetUser after a failed change: cached %v, %v", !notCached(resp), err)
+	}
+
+	// A change invalidates what it's related to, and nothing else.
+	if _, _, err := conn.GetUser("bob"); err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if _, err := conn.CreateGroup("staff"); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if _, resp, err := conn.GetUser("alice"); err != nil || notCached(resp) {
+		t.Errorf("GetUser after a change to groups: cached %v, %v", !notCached(resp), err)
+	}
+	if _, _, err := conn.UpdateUser("alice", UpdatedUser{Name: "alice", Admin: true}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if _, resp, err := conn.GetUser("alice"); err != nil || !notCached(resp) {
+		t.Errorf("GetUser after a change to the user: cached %v, %v", !notCached(resp), err)
+	}
+	if _, resp, err := conn.GetUser("bob"); err != nil || notCached(resp) {
+		t.Errorf("GetUser of another user after a change: cached %v, %v", !notCached(resp), err)
+	}
+}
+
+func TestDiskCacheInvalidate(t *testing.T) {
+	cache := NewDiskCache(t.TempDir())
+	paths := []string{"/", "/users", "/users/alice", "/users/alice/tokens", "/users/alicia", "/users/bob", "/groups"}
+	for _, path := range paths {
+		if err := cache.Put(path, "GET "+path, CacheEntry{Time: time.Now(), StatusCode: http.StatusOK}); err != nil {
+			t.Fatalf("Put(%s): %v", path, err)
+		}
+	}
+	if err := cache.Invalidate("/users/alice"); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	kept := map[string]bool{"/": true, "/users/alicia": true, "/users/bob": true, "/groups": true}
+	for _, path := range paths {
+		if _, ok := cache.Get(path, "GET "+path); ok != kept[path] {
+			t.Errorf("after invalidating /users/alice, %s is cached: %v, want %v", path, ok, kept[path])
+		}
+	}
+}
+
+func TestDiskCacheLifetime(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewDiskCache(dir)
+	if err := cache.Put("/users/alice", "old", CacheEntry{}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	// Only the first Put of a process removes old entries, so forget that one.
+	sweptMu.Lock()
+	delete(swept, dir)
+	sweptMu.Unlock()
+	old := time.Now().Add(-DiskCacheLifetime - time.Hour)
+	if err := os.Chtimes(diskCache(dir).file("/users/alice", "old"), old, old); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	if err := cache.Put("/groups", "new", CacheEntry{}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := cache.Get("/users/alice", "old"); ok {
+		t.Errorf("an entry older than DiskCacheLifetime is still cached")
+	}
+	if _, err := os.Stat(diskCache(dir).dir("/users")); !os.IsNotExist(err) {
+		t.Errorf("the emptied directory for /users is still there: %v", err)
+	}
+	if _, ok := cache.Get("/groups", "new"); !ok {
+		t.Errorf("the new entry isn't cached")
+	}
+}
+
+func TestCacheKeepsTokensOffDisk(t *testing.T) {
+	_, conn := newTestHub(t)
+	dir := t.TempDir()
+	conn.Cache = NewDiskCache(dir)
+	if _, _, err := conn.GetTokenOwner("alice-token"); err != nil {
+		t.Fatalf("GetTokenOwner: %v", err)
+	}
+	if _, _, err := conn.GetUser("alice"); err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+
+	files := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		files++
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var entry CacheEntry
+		if err := json.Unmarshal(b, &entry); err != nil {
+			return err
+		}
+		for _, token := range []string{"alice-token", conn.Token} {
+			if strings.Contains(path+string(b)+string(entry.Body), token) {
+				t.Errorf("the cache file %s has the token %q in it", path, token)
+			}
+		}
+		return nil
+	})
+	if err != nil || files != 2 {
+		t.Fatalf("the cache has %d files, %v; want 2", files, err)
+	}
+}
+
+func TestOffline(t *testing.T) {
+	hub, conn := newTestHub(t)
+	conn.Cache = NewDiskCache(t.TempDir())
+	if _, _, err := conn.GetUser("alice"); err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if _, _, err := conn.GetAllUsers(); err != nil {
+		t.Fatalf("GetAllUsers: %v", err)
+	}
+	before := len(hub.Requests())
+
+	conn.Offline = true
+	u, resp, err := conn.GetUser("alice")
+	if err != nil || u.Name != "alice" || notCached(resp) {
+		t.Errorf("GetUser offline = %q, %v", u.Name, err)
+	}
+	if users, _, err := conn.GetAllUsers(); err != nil || len(users) != 3 {
+		t.Errorf("GetAllUsers offline = %d users, %v", len(users), err)
+	}
+	if _, _, err := conn.GetUser("bob"); !errors.Is(err, ErrOffline) {
+		t.Errorf("GetUser offline of a user never asked for: err = %v, want ErrOffline", err)
+	}
+	if _, _, err := conn.CreateUser("carol", false); !errors.Is(err, ErrOffline) {
+		t.Errorf("CreateUser offline: err = %v, want ErrOffline", err)
+	}
+	if after := len(hub.Requests()); after != before {
+		t.Errorf("the hub got %d requests while offline", after-before)
+	}
+}
+
+func notCached(resp *http.Response) bool {
+	_, ok := CachedAt(resp)
+	return !ok
+}
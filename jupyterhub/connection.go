@@ -1,34 +1,50 @@
 package jupyterhub
 
+import (
+	"context"
+	"time"
+)
+
 // Connection is the data required to talk to a JuptyterHub hub.
 // Connection contains necessary data to connect to the JupytherHub API
 // HubURL - the connection end point
 // token - the Token needed for Authorization.
+// Transport - TLS, proxy and timeout settings for reaching the hub.
+// Retry - how to retry failed requests.
+// Logger - where to log requests, nothing is logged if nil.
+// Concurrency - how many requests of a batch to make at once, DefaultConcurrency if 0.
+// Cache - where to keep GET responses, nothing is cached if nil (see ResponseCache).
+// Offline - answer only from the Cache, never asking the hub.
+// MaxAge - answer from the Cache, without asking the hub, when it has a response this recent.
 // and a name for identification.
+// Requests are made with context.Background() unless a context is
+// provided with WithContext.
 type Connection struct {
-	Name   string
-	HubURL string
-	Token  string
-	Auth   Auth
+	Name        string
+	HubURL      string
+	Token       string
+	Auth        Auth
+	Transport   Transport
+	Retry       RetryPolicy
+	Logger      Logger
+	Concurrency int
+	Cache       ResponseCache
+	Offline     bool
+	MaxAge      time.Duration
+
+	ctx context.Context
 }
 
-// Auth holds paramaters to handle OAuth outhorization commands.
+// Auth holds paramaters to handle OAuth outhorization commands,
+// they identify the OAuth client that Login logs in as.
 type Auth struct {
 	ClientID     string
 	ClientSecret string
 	RedirectURL  string
 }
 
-// YAML variables. These show up in viper, but the application
-// sets and uses them using the config.
-const (
-	clientIDKey     = "clientID"
-	clientSecretKey = "clientSecret"
-	redirectURLKey  = "redirectURL"
-)
-
 // UpdateAuth sets only non-blank ("") values of auth.
-func (a Auth) UpdateAuth(update Auth) {
+func (a *Auth) UpdateAuth(update Auth) {
 	if update.ClientID != "" {
 		a.ClientID = update.ClientID
 	}
@@ -39,8 +55,3 @@ func (a Auth) UpdateAuth(update Auth) {
 		a.RedirectURL = update.RedirectURL
 	}
 }
-
-// InitAuth gets defaul values from  the connection
-func InitAuth(connectionName string) {
-	panic("InitAuth not implemented yet.")
-}
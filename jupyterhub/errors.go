@@ -0,0 +1,124 @@
+package jupyterhub
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// HubError is returned when the hub answers a request with an
+// HTTP error status (300 or above).
+type HubError struct {
+	Method     string // HTTP method of the failed request.
+	URL        string // URL of the failed request.
+	StatusCode int    // e.g. 404
+	Status     string // e.g. "404 Not Found"
+	Message    string // The hub's own explanation, from the "message" field of the JSON body.
+	Hint       string // What to check, for the more common failures.
+}
+
+func (e *HubError) Error() string {
+	s := fmt.Sprintf("HTTP Request %s:%s, HTTP Response: %s.", e.Method, e.URL, e.Status)
+	if e.Message != "" {
+		s = fmt.Sprintf("%s %s.", s, strings.TrimSuffix(e.Message, "."))
+	}
+	if e.Hint != "" {
+		s = fmt.Sprintf("%s %s", s, e.Hint)
+	}
+	return s
+}
+
+// IsBadRequest is true if err is a HubError with 400 Bad Request.
+func IsBadRequest(err error) bool {
+	return hasStatus(err, http.StatusBadRequest)
+}
+
+// IsNotFound is true if err is a HubError with 404 Not Found.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized is true if err is a HubError with 401 Unauthorized.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden is true if err is a HubError with 403 Forbidden.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsConflict is true if err is a HubError with 409 Conflict,
+// e.g. when creating something that already exists.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+func hasStatus(err error, status int) bool {
+	var hubErr *HubError
+	return errors.As(err, &hubErr) && hubErr.StatusCode == status
+}
+
+// VersionError is returned, without asking the hub, by calls that
+// need a newer version of JupyterHub than the hub is running.
+type VersionError struct {
+	Feature Feature
+	Version HubVersion // The hub's version.
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("%s requires JupyterHub >= %s, the hub is running %s", e.Feature.Name, e.Feature.Since, e.Version)
+}
+
+// IsVersionError is true if err is a VersionError.
+func IsVersionError(err error) bool {
+	var versionErr *VersionError
+	return errors.As(err, &versionErr)
+}
+
+// hubMessage is the JSON the hub sends along with most errors.
+type hubMessage struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// Returns a HubError if not 200.
+// To pick up the hub's message the body is read, and then
+// replaced so that it can be read again.
+func checkReturnCode(resp *http.Response) (err error) {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+
+	hubErr := &HubError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+	if resp.Request != nil {
+		hubErr.Method = resp.Request.Method
+		hubErr.URL = resp.Request.URL.String()
+	}
+
+	if body, readErr := ioutil.ReadAll(resp.Body); readErr == nil {
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		var m hubMessage
+		if json.Unmarshal(body, &m) == nil {
+			hubErr.Message = m.Message
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		hubErr.Hint = "Check for valid argument (user, group etc)."
+	case http.StatusUnauthorized:
+		hubErr.Hint = "Check for valid token."
+	case http.StatusForbidden:
+		hubErr.Hint = "Check for valid token and token user must be an admin"
+	}
+	return hubErr
+}
@@ -1,6 +1,7 @@
 package jupyterhub
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 )
@@ -9,10 +10,12 @@ import (
 type Groups []Group
 
 // Group is the hub respresentation of a group of users.
+// Properties are free-form, and only kept by JupyterHub 3+.
 type Group struct {
-	Name      string   `json:"name"`
-	Kind      string   `json:"kind"`
-	UserNames []string `json:"users"`
+	Name       string                 `json:"name"`
+	Kind       string                 `json:"kind"`
+	UserNames  []string               `json:"users"`
+	Properties map[string]interface{} `json:"properties"`
 }
 
 // UserGroup is state requried for Adding/Removing a user to a group
@@ -28,11 +31,28 @@ func (conn Connection) GetGroup(name string) (group Group, resp *http.Response,
 }
 
 // GetGroups returns all of the groups on the hub.
+// Groups are fetched a page of DefaultPageSize at a time,
+// see EachGroupsPage to process them as they arrive instead.
 func (conn Connection) GetGroups() (groups Groups, resp *http.Response, err error) {
-	resp, err = conn.Get("/groups", &groups)
+	resp, err = conn.EachGroupsPage(DefaultPageSize, func(page Groups, _ Pagination) error {
+		groups = append(groups, page...)
+		return nil
+	})
 	return groups, resp, err
 }
 
+// EachGroupsPage gets the hub's groups pageSize at a time, calling f with each page
+// as it arrives. It stops early if f returns an error, and returns that error.
+func (conn Connection) EachGroupsPage(pageSize int, f func(Groups, Pagination) error) (resp *http.Response, err error) {
+	return conn.eachPage("/groups", nil, pageSize, func(items json.RawMessage, p Pagination) error {
+		var groups Groups
+		if err := json.Unmarshal(items, &groups); err != nil {
+			return err
+		}
+		return f(groups, p)
+	})
+}
+
 // CreateGroup creates a group with name on the hub.
 func (conn Connection) CreateGroup(name string) (resp *http.Response, err error) {
 	resp, err = conn.Post(fmt.Sprintf("/groups/%s", name), nil, nil)
@@ -45,6 +65,19 @@ func (conn Connection) DeleteGroup(name string) (resp *http.Response, err error)
 	return resp, err
 }
 
+// SetGroupProperties replaces all of the properties of the group name with properties,
+// returning the updated group.
+func (conn Connection) SetGroupProperties(name string, properties map[string]interface{}) (group Group, resp *http.Response, err error) {
+	if err = conn.require(GroupProperties); err != nil {
+		return group, resp, err
+	}
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+	resp, err = conn.Put(fmt.Sprintf("/groups/%s/properties", name), properties, &group)
+	return group, resp, err
+}
+
 // AddUserToGroup adds the UserGroup.UserNames to the group UserGroup.Name on the hub.
 func (conn Connection) AddUserToGroup(user UserGroup) (returnUsers UserGroup, resp *http.Response, err error) {
 	resp, err = conn.Post(fmt.Sprintf("/groups/%s/users", user.Name), user, &returnUsers)
@@ -56,3 +89,21 @@ func (conn Connection) RemoveUserFromGroup(user UserGroup) (returnUsers UserGrou
 	resp, err = conn.Delete(fmt.Sprintf("/groups/%s/users", user.Name), user, &returnUsers)
 	return returnUsers, resp, err
 }
+
+// AddUserToGroups adds the user to each of the groups, making
+// up to conn.Concurrency requests at once. The results are named by group.
+func (conn Connection) AddUserToGroups(username string, groups []string) BatchResults {
+	return conn.batchNames(groups, func(group string) (*http.Response, error) {
+		_, resp, err := conn.AddUserToGroup(UserGroup{Name: group, UserNames: []string{username}})
+		return resp, err
+	})
+}
+
+// RemoveUserFromGroups removes the user from each of the groups, making
+// up to conn.Concurrency requests at once. The results are named by group.
+func (conn Connection) RemoveUserFromGroups(username string, groups []string) BatchResults {
+	return conn.batchNames(groups, func(group string) (*http.Response, error) {
+		_, resp, err := conn.RemoveUserFromGroup(UserGroup{Name: group, UserNames: []string{username}})
+		return resp, err
+	})
+}
@@ -0,0 +1,56 @@
+package jupyterhub
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGroups(t *testing.T) {
+	_, conn := newTestHub(t)
+
+	resp, err := conn.CreateGroup("students")
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if _, err := conn.CreateGroup("students"); !IsConflict(err) {
+		t.Errorf("CreateGroup of an existing group: err = %v, want 409", err)
+	}
+
+	g, _, err := conn.AddUserToGroup(UserGroup{Name: "students", UserNames: []string{"alice", "bob"}})
+	if err != nil || len(g.UserNames) != 2 {
+		t.Errorf("AddUserToGroup = %+v, %v", g, err)
+	}
+	if _, _, err := conn.AddUserToGroup(UserGroup{Name: "students", UserNames: []string{"nobody"}}); !IsBadRequest(err) {
+		t.Errorf("AddUserToGroup of a missing user: err = %v, want 400", err)
+	}
+	u, _, _ := conn.GetUser("alice")
+	if len(u.Groups) != 1 || u.Groups[0] != "students" {
+		t.Errorf("alice's groups = %v, want [students]", u.Groups)
+	}
+
+	g, _, err = conn.RemoveUserFromGroup(UserGroup{Name: "students", UserNames: []string{"bob"}})
+	if err != nil || len(g.UserNames) != 1 || g.UserNames[0] != "alice" {
+		t.Errorf("RemoveUserFromGroup = %+v, %v", g, err)
+	}
+
+	group, _, err := conn.SetGroupProperties("students", map[string]interface{}{"course": "101"})
+	if err != nil || group.Properties["course"] != "101" {
+		t.Errorf("SetGroupProperties = %+v, %v", group, err)
+	}
+	group, _, err = conn.GetGroup("students")
+	if err != nil || group.Properties["course"] != "101" {
+		t.Errorf("GetGroup = %+v, %v", group, err)
+	}
+
+	groups, _, err := conn.GetGroups()
+	if err != nil || len(groups) != 1 {
+		t.Errorf("GetGroups = %+v, %v", groups, err)
+	}
+
+	if _, err := conn.DeleteGroup("students"); err != nil {
+		t.Errorf("DeleteGroup: %v", err)
+	}
+	if _, _, err := conn.GetGroup("students"); !IsNotFound(err) {
+		t.Errorf("GetGroup of a deleted group: err = %v, want 404", err)
+	}
+}
@@ -0,0 +1,138 @@
+package jupyterhub
+
+import (
+	"testing"
+
+	"github.com/jdrivas/sponde/jupyterhubtest"
+)
+
+// newTestHub starts an in-memory hub, with the users alice (whose token is
+// "alice-token") and bob, and returns it with an admin connection to it.
+func newTestHub(t *testing.T) (*jupyterhubtest.Hub, Connection) {
+	t.Helper()
+	hub := jupyterhubtest.NewHub()
+	hub.AddUserToken("alice", "alice-token")
+	hub.AddUser("bob", false)
+	srv := jupyterhubtest.NewServer(hub)
+	t.Cleanup(srv.Close)
+	return hub, Connection{Name: "test", HubURL: srv.APIURL, Token: jupyterhubtest.AdminToken}
+}
+
+// countRequests is how many of the hub's requests were request, e.g. "GET /users/alice".
+func countRequests(hub *jupyterhubtest.Hub, request string) (n int) {
+	for _, r := range hub.Requests() {
+		if r == request {
+			n++
+		}
+	}
+	return n
+}
+
+func TestGetVersion(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.Version = "4.1.6"
+	conn.Token = ""
+
+	v, _, err := conn.GetVersion()
+	if err != nil {
+		t.Fatalf("GetVersion: %v", err)
+	}
+	if v.Version != "4.1.6" {
+		t.Errorf("Version = %q, want %q", v.Version, "4.1.6")
+	}
+}
+
+func TestGetInfo(t *testing.T) {
+	_, conn := newTestHub(t)
+	info, _, err := conn.GetInfo()
+	if err != nil {
+		t.Fatalf("GetInfo: %v", err)
+	}
+	if info.Version != jupyterhubtest.DefaultVersion || info.Spawner.Class == "" {
+		t.Errorf("GetInfo = %+v", info)
+	}
+}
+
+func TestBadToken(t *testing.T) {
+	_, conn := newTestHub(t)
+	conn.Token = "not-a-token"
+	if _, _, err := conn.GetUser("alice"); !IsForbidden(err) {
+		t.Errorf("GetUser with a bad token: err = %v, want 403", err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	hub, conn := newTestHub(t)
+	resp, err := conn.Shutdown()
+	if err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if resp.StatusCode != 202 || !hub.ShutdownRequested() {
+		t.Errorf("Shutdown: status %d, requested %v", resp.StatusCode, hub.ShutdownRequested())
+	}
+}
+
+func TestWhoami(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.AddService("announcement", "http://127.0.0.1:8889", "service-token", false)
+
+	tests := []struct {
+		token, kind, name string
+		admin             bool
+	}{
+		{jupyterhubtest.AdminToken, UserKind, "admin", true},
+		{"alice-token", UserKind, "alice", false},
+		{"service-token", ServiceKind, "announcement", false},
+	}
+	for _, tt := range tests {
+		c := conn
+		c.Token = tt.token
+		owner, _, err := c.Whoami()
+		if err != nil {
+			t.Errorf("Whoami as %s: %v", tt.name, err)
+			continue
+		}
+		if owner.Kind != tt.kind || owner.Name() != tt.name || owner.Admin() != tt.admin {
+			t.Errorf("Whoami as %s = %s %s admin %v, want %s %s admin %v", tt.name,
+				owner.Kind, owner.Name(), owner.Admin(), tt.kind, tt.name, tt.admin)
+		}
+		if tt.kind == UserKind && len(owner.Scopes()) == 0 {
+			t.Errorf("Whoami as %s has no scopes", tt.name)
+		}
+
+		owner, _, err = conn.GetTokenOwner(tt.token)
+		if err != nil || owner.Name() != tt.name {
+			t.Errorf("GetTokenOwner(%s's token) = %s, %v", tt.name, owner.Name(), err)
+		}
+	}
+
+	if _, _, err := conn.GetTokenOwner("not-a-token"); !IsNotFound(err) {
+		t.Errorf("GetTokenOwner of an unknown token: err = %v, want 404", err)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.StartServer("alice", "")
+
+	routes, _, err := conn.GetProxy()
+	if err != nil {
+		t.Fatalf("GetProxy: %v", err)
+	}
+	if !routes["/"].Data.Hub {
+		t.Errorf("no route to the hub in %v", routes)
+	}
+	if r, ok := routes["/user/alice/"]; !ok || r.Data.User != "alice" {
+		t.Errorf("no route to alice's server in %v", routes)
+	}
+
+	if _, err := conn.SyncProxy(); err != nil {
+		t.Errorf("SyncProxy: %v", err)
+	}
+	if _, err := conn.UpdateProxy(ProxyUpdate{APIURL: "http://proxy:8001", AuthToken: "secret"}); err != nil {
+		t.Errorf("UpdateProxy: %v", err)
+	}
+	if got := hub.ProxyAPIURL(); got != "http://proxy:8001" {
+		t.Errorf("proxy API URL = %q, want %q", got, "http://proxy:8001")
+	}
+}
@@ -0,0 +1,120 @@
+package jupyterhub
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+)
+
+// LogLevel orders log messages from the least to the most detailed.
+type LogLevel int
+
+// Log levels.
+const (
+	LogWarn  LogLevel = iota // Things that work, but shouldn't be relied on (e.g. disabled TLS verification).
+	LogInfo                  // One line for each request, retry and failure.
+	LogDebug                 // Full request and response dumps.
+)
+
+var levelNames = map[LogLevel]string{
+	LogWarn:  "WARN",
+	LogInfo:  "INFO",
+	LogDebug: "DEBUG",
+}
+
+func (l LogLevel) String() string {
+	if n, ok := levelNames[l]; ok {
+		return n
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// Logger receives the connection's log messages.
+// Enabled lets the connection skip building expensive
+// messages (like request dumps) that won't be logged.
+// Tokens have already been removed from messages by the time
+// they get to Logf, see Redact.
+type Logger interface {
+	Enabled(level LogLevel) bool
+	Logf(level LogLevel, format string, args ...interface{})
+}
+
+// NewLogger returns a Logger that writes messages at level and below
+// to w, each with a timestamp and its level.
+func NewLogger(w io.Writer, level LogLevel) Logger {
+	return &writerLogger{
+		level:  level,
+		logger: log.New(w, "", log.LstdFlags),
+	}
+}
+
+type writerLogger struct {
+	level  LogLevel
+	logger *log.Logger
+}
+
+func (l *writerLogger) Enabled(level LogLevel) bool {
+	return level <= l.level
+}
+
+func (l *writerLogger) Logf(level LogLevel, format string, args ...interface{}) {
+	if l.Enabled(level) {
+		l.logger.Printf("%-5s %s", level, fmt.Sprintf(format, args...))
+	}
+}
+
+// NopLogger discards everything. It's what a connection uses
+// when it doesn't have a Logger.
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Enabled(LogLevel) bool                 { return false }
+func (nopLogger) Logf(LogLevel, string, ...interface{}) {}
+
+// Token shaped things to keep out of the logs.
+var redactions = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// Authorization: token abc, Authorization: Bearer abc
+	{regexp.MustCompile(`(?i)(authorization:\s*(?:token|bearer)\s+)\S+`), "${1}[REDACTED]"},
+	// /authorizations/token/<token>
+	{regexp.MustCompile(`(/authorizations/token/)[^/?#\s"]+`), "${1}[REDACTED]"},
+	// ?token=abc, &code=abc ...
+	{regexp.MustCompile(`([?&](?:token|code|code_verifier|client_secret)=)[^&#\s"]+`), "${1}[REDACTED]"},
+	// "token": "abc", "api_token": "abc" ... in JSON bodies.
+	{regexp.MustCompile(`("(?:token|api_token|access_token|refresh_token|auth_token|client_secret)"\s*:\s*)"[^"]*"`), `${1}"[REDACTED]"`},
+	// Token:"abc" ... in decoded objects logged with %#v.
+	{regexp.MustCompile(`\b((?:Token|APIToken|AccessToken|RefreshToken|AuthToken|ClientSecret):)"[^"]*"`), `${1}"[REDACTED]"`},
+}
+
+// Redact returns s with tokens in Authorization headers, token URLs
+// and JSON token fields replaced by "[REDACTED]".
+func Redact(s string) string {
+	for _, r := range redactions {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	return s
+}
+
+// logger returns the connection's logger, never nil.
+func (conn Connection) logger() Logger {
+	if conn.Logger != nil {
+		return conn.Logger
+	}
+	return NopLogger
+}
+
+// logf redacts and logs the message.
+func (conn Connection) logf(level LogLevel, format string, args ...interface{}) {
+	l := conn.logger()
+	if l.Enabled(level) {
+		l.Logf(level, "%s", Redact(fmt.Sprintf(format, args...)))
+	}
+}
+
+func (conn Connection) logEnabled(level LogLevel) bool {
+	return conn.logger().Enabled(level)
+}
@@ -0,0 +1,73 @@
+package jupyterhub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Authorization: token abc123", "Authorization: token [REDACTED]"},
+		{"authorization: Bearer abc123", "authorization: Bearer [REDACTED]"},
+		{"GET /authorizations/token/abc123?x=1", "GET /authorizations/token/[REDACTED]?x=1"},
+		{"/oauth2/token?code=abc&code_verifier=def&state=s", "/oauth2/token?code=[REDACTED]&code_verifier=[REDACTED]&state=s"},
+		{`{"note":"n","token": "abc","api_token":"def"}`, `{"note":"n","token": "[REDACTED]","api_token":"[REDACTED]"}`},
+		{`&jupyterhub.APIToken{ID:"a1", Token:"abc"}`, `&jupyterhub.APIToken{ID:"a1", Token:"[REDACTED]"}`},
+		{"GET /users/alice", "GET /users/alice"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoggingRedactsTokens(t *testing.T) {
+	_, conn := newTestHub(t)
+	var log bytes.Buffer
+	conn.Logger = NewLogger(&log, LogDebug)
+
+	created, _, err := conn.CreateToken("alice", TokenRequest{Note: "logged"})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	if log.Len() == 0 {
+		t.Fatalf("nothing was logged")
+	}
+	for _, secret := range []string{created.Token, conn.Token} {
+		if strings.Contains(log.String(), secret) {
+			t.Errorf("the log has a token in it:\n%s", log.String())
+		}
+	}
+}
+
+func TestInsecureWarningWithoutLogger(t *testing.T) {
+	var warnings bytes.Buffer
+	saved := Warnings
+	Warnings = &warnings
+	defer func() { Warnings = saved }()
+
+	// Forget any warnings from earlier runs, e.g. with -count.
+	hubs := []string{"https://hub.example.com", "https://other.example.com"}
+	clientsMu.Lock()
+	for _, hub := range hubs {
+		delete(warned, hub)
+	}
+	clientsMu.Unlock()
+
+	// Both hubs share one client, but each is warned about, once.
+	for _, hub := range append(hubs, hubs...) {
+		conn := Connection{HubURL: hub, Transport: Transport{InsecureSkipVerify: true}}
+		if _, err := conn.httpClient(); err != nil {
+			t.Fatalf("httpClient: %v", err)
+		}
+	}
+	for _, hub := range hubs {
+		if n := strings.Count(warnings.String(), "DISABLED for "+hub+"."); n != 1 {
+			t.Errorf("warned about %s %d times, want once:\n%s", hub, n, warnings.String())
+		}
+	}
+}
@@ -1,5 +1,188 @@
 package jupyterhub
 
 import (
-// "net/http"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
 )
+
+// DefaultRedirectURL is where Login listens for the hub to send
+// the browser back to, when the connection's Auth doesn't have a RedirectURL.
+// The hub's OAuth client must be registered with the same redirect URL.
+const DefaultRedirectURL = "http://127.0.0.1:8765/oauth_callback"
+
+// AccessToken is the hub's answer to a successful login.
+// Token is used just like an API token.
+type AccessToken struct {
+	Token     string `json:"access_token"`
+	TokenType string `json:"token_type"`
+	Scope     string `json:"scope"`
+	ExpiresIn int    `json:"expires_in"` // Seconds, 0 if it doesn't expire.
+}
+
+// Login logs in to the hub with the OAuth2 authorization code flow (with PKCE),
+// as the OAuth client in the connection's Auth, and returns the new access token.
+// It listens on the Auth's RedirectURL, which must be a loopback http URL,
+// and calls open with the hub's authorization URL for the user to visit in a browser.
+// Once the user has logged in and the hub has sent the browser back,
+// the authorization code is exchanged for a token.
+// Login waits for the user until the connection's context is done.
+func (conn Connection) Login(open func(authURL string) error) (token AccessToken, resp *http.Response, err error) {
+	if conn.Auth.ClientID == "" {
+		return token, resp, fmt.Errorf("login needs an OAuth client id")
+	}
+	redirectURL := conn.Auth.RedirectURL
+	if redirectURL == "" {
+		redirectURL = DefaultRedirectURL
+	}
+	redirect, err := url.Parse(redirectURL)
+	if err != nil {
+		return token, resp, fmt.Errorf("bad redirect URL %q: %v", redirectURL, err)
+	}
+	if redirect.Scheme != "http" || !isLoopback(redirect.Hostname()) {
+		return token, resp, fmt.Errorf("redirect URL %q must be http on a loopback address (e.g. %s)", redirectURL, DefaultRedirectURL)
+	}
+
+	verifier, err := randomString(32)
+	if err != nil {
+		return token, resp, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return token, resp, err
+	}
+
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return token, resp, fmt.Errorf("couldn't listen for the hub's redirect: %v", err)
+	}
+	codes := make(chan authResult, 1)
+	mux := http.NewServeMux()
+	path := redirect.Path
+	if path == "" {
+		path = "/"
+	}
+	mux.HandleFunc(path, callbackHandler(state, codes))
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", conn.Auth.ClientID)
+	q.Set("redirect_uri", redirectURL)
+	q.Set("state", state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	conn.discover()
+	authURL := fmt.Sprintf("%s/oauth2/authorize?%s", conn.apiURL(), q.Encode())
+	conn.logf(LogInfo, "Waiting for login at %s", redirectURL)
+	if err = open(authURL); err != nil {
+		return token, resp, err
+	}
+
+	var result authResult
+	select {
+	case result = <-codes:
+	case <-conn.Context().Done():
+		return token, resp, conn.Context().Err()
+	}
+	if result.err != nil {
+		return token, resp, result.err
+	}
+
+	return conn.exchangeCode(result.code, redirectURL, verifier)
+}
+
+// exchangeCode trades an authorization code for an access token.
+func (conn Connection) exchangeCode(code, redirectURL, verifier string) (token AccessToken, resp *http.Response, err error) {
+	client, err := conn.httpClient()
+	if err != nil {
+		return token, resp, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	form.Set("client_id", conn.Auth.ClientID)
+	form.Set("code_verifier", verifier)
+	if conn.Auth.ClientSecret != "" {
+		form.Set("client_secret", conn.Auth.ClientSecret)
+	}
+
+	req := conn.newRequest(http.MethodPost, "/oauth2/token", strings.NewReader(form.Encode()))
+	// We don't have a token yet, that's the point.
+	req.Header.Del("Authorization")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err = conn.sendReq(client, req, &token)
+	if err == nil && token.Token == "" {
+		err = fmt.Errorf("the hub didn't return an access token")
+	}
+	return token, resp, err
+}
+
+// authResult is what the hub sent back to the redirect URL.
+type authResult struct {
+	code string
+	err  error
+}
+
+// callbackHandler receives the browser back from the hub, checks that
+// it's the answer to our request and sends the code (or error) on codes.
+// Anything after the first answer is ignored.
+func callbackHandler(state string, codes chan<- authResult) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var result authResult
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "Login failed: this isn't the login sponde is waiting for.", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			result.err = fmt.Errorf("login failed: %s %s", q.Get("error"), q.Get("error_description"))
+		case q.Get("code") == "":
+			result.err = fmt.Errorf("login failed: the hub didn't send an authorization code")
+		default:
+			result.code = q.Get("code")
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if result.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "<html><body><p>%s</p></body></html>", html.EscapeString(result.err.Error()))
+		} else {
+			fmt.Fprintf(w, "<html><body><p>Logged in, you can close this window and go back to sponde.</p></body></html>")
+		}
+		select {
+		case codes <- result:
+		default:
+		}
+	}
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// randomString returns n random bytes, URL safe base64 encoded.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,140 @@
+package jupyterhub
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// oauthHub is a hub's token endpoint, for logging in to. It checks the code,
+// and that the code_verifier goes with the challenge the login was started with.
+type oauthHub struct {
+	challenge string // The code_challenge of the authorization URL.
+	exchanges int
+}
+
+func (h *oauthHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/hub/api/oauth2/token" {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	h.exchanges++
+	r.ParseForm()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case r.Header.Get("Authorization") != "":
+		http.Error(w, "unexpected Authorization header", http.StatusBadRequest)
+	case r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("code") != "the-code":
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+	case r.PostForm.Get("client_id") != "sponde" || r.PostForm.Get("redirect_uri") == "":
+		http.Error(w, "invalid_client", http.StatusBadRequest)
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != h.challenge:
+		http.Error(w, "code_verifier doesn't match the code_challenge", http.StatusBadRequest)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AccessToken{Token: "the-access-token", TokenType: "Bearer", Scope: "identify"})
+	}
+}
+
+// login logs in to an oauthHub, with the browser coming back to the redirect URL
+// with the query callback returns, given the authorization URL's query.
+// The callback's response code is returned, with the results of Login.
+func login(t *testing.T, ctx context.Context, callback func(q url.Values) url.Values) (hub *oauthHub, status int, token AccessToken, err error) {
+	t.Helper()
+	hub = &oauthHub{}
+	srv := httptest.NewServer(hub)
+	t.Cleanup(srv.Close)
+
+	// A free port on the loopback address to come back to.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	redirect := fmt.Sprintf("http://%s/oauth_callback", l.Addr())
+	l.Close()
+
+	conn := Connection{HubURL: srv.URL + "/hub/api", Auth: Auth{ClientID: "sponde", RedirectURL: redirect}}
+	open := func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+		q := u.Query()
+		if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "sponde" || q.Get("redirect_uri") != redirect {
+			return fmt.Errorf("bad authorization URL %s", authURL)
+		}
+		hub.challenge = q.Get("code_challenge")
+		resp, err := http.Get(redirect + "?" + callback(q).Encode())
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		status = resp.StatusCode
+		return nil
+	}
+	token, _, err = conn.WithContext(ctx).Login(open)
+	return hub, status, token, err
+}
+
+func TestLogin(t *testing.T) {
+	hub, status, token, err := login(t, context.Background(), func(q url.Values) url.Values {
+		return url.Values{"state": {q.Get("state")}, "code": {"the-code"}}
+	})
+	if err != nil || token.Token != "the-access-token" {
+		t.Fatalf("Login = %+v, %v", token, err)
+	}
+	if status != http.StatusOK || hub.exchanges != 1 {
+		t.Errorf("callback status %d, %d code exchanges; want 200, 1", status, hub.exchanges)
+	}
+}
+
+func TestLoginStateMismatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	hub, status, _, err := login(t, ctx, func(q url.Values) url.Values {
+		return url.Values{"state": {"not-" + q.Get("state")}, "code": {"the-code"}}
+	})
+	if status != http.StatusBadRequest {
+		t.Errorf("callback with the wrong state: status %d, want 400", status)
+	}
+	// Login keeps waiting for the real answer.
+	if !errors.Is(err, context.DeadlineExceeded) || hub.exchanges != 0 {
+		t.Errorf("Login with the wrong state: %v, %d code exchanges; want a timeout and none", err, hub.exchanges)
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		query func(state string) url.Values
+		want  string
+	}{
+		{"error", func(state string) url.Values {
+			return url.Values{"state": {state}, "error": {"access_denied"}, "error_description": {"no thanks"}}
+		}, "access_denied no thanks"},
+		{"no code", func(state string) url.Values {
+			return url.Values{"state": {state}}
+		}, "didn't send an authorization code"},
+	}
+	for _, tt := range tests {
+		hub, status, _, err := login(t, context.Background(), func(q url.Values) url.Values {
+			return tt.query(q.Get("state"))
+		})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Login with %s: err = %v, want %q", tt.name, err, tt.want)
+		}
+		if status != http.StatusBadRequest || hub.exchanges != 0 {
+			t.Errorf("Login with %s: callback status %d, %d code exchanges; want 400, none", tt.name, status, hub.exchanges)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package jupyterhub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PaginationContentType is the Accept type that asks a JupyterHub 2+ hub
+// to wrap lists in an object with paging information.
+const PaginationContentType = "application/jupyterhub-pagination+json"
+
+// DefaultPageSize is the number of items requested for each page.
+const DefaultPageSize = 200
+
+// Pagination is the hub's description of a page of results.
+// Next is nil on the last page. CachedAt is when the hub sent the page,
+// if it came from the cache, and is zero otherwise.
+type Pagination struct {
+	Offset   int       `json:"offset"`
+	Limit    int       `json:"limit"`
+	Total    int       `json:"total"`
+	Next     *NextPage `json:"next"`
+	CachedAt time.Time `json:"-"`
+}
+
+// NextPage locates the page after this one.
+type NextPage struct {
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+	URL    string `json:"url"`
+}
+
+// page is the paginated response body.
+type page struct {
+	Items      json.RawMessage `json:"items"`
+	Pagination *Pagination     `json:"_pagination"`
+}
+
+// getPage gets a single page of the list at path, starting at offset.
+// Hubs older than 2.0 ignore the paging parameters and return
+// the whole list, which comes back as the one and only page.
+func (conn Connection) getPage(path string, query url.Values, offset, limit int) (items json.RawMessage, pagination Pagination, resp *http.Response, err error) {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("offset", strconv.Itoa(offset))
+	q.Set("limit", strconv.Itoa(limit))
+
+	header := http.Header{}
+	header.Set("Accept", PaginationContentType)
+
+	var raw json.RawMessage
+	resp, err = conn.send(http.MethodGet, fmt.Sprintf("%s?%s", path, q.Encode()), header, nil, &raw)
+	if err != nil {
+		return items, pagination, resp, err
+	}
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		// Unpaginated list.
+		var list []json.RawMessage
+		err = json.Unmarshal(raw, &list)
+		pagination = Pagination{Offset: 0, Limit: len(list), Total: len(list)}
+		return raw, pagination, resp, err
+	}
+
+	var p page
+	if err = json.Unmarshal(raw, &p); err == nil {
+		items = p.Items
+		if p.Pagination != nil {
+			pagination = *p.Pagination
+		}
+	}
+	return items, pagination, resp, err
+}
+
+// eachPage calls f with each page of the list at path, in order,
+// until there are no more pages or f returns an error.
+// The response returned is the last one received.
+func (conn Connection) eachPage(path string, query url.Values, pageSize int, f func(json.RawMessage, Pagination) error) (resp *http.Response, err error) {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	offset := 0
+	for {
+		var items json.RawMessage
+		var pagination Pagination
+		items, pagination, resp, err = conn.getPage(path, query, offset, pageSize)
+		if err != nil {
+			return resp, err
+		}
+		pagination.CachedAt, _ = CachedAt(resp)
+		if err = f(items, pagination); err != nil {
+			return resp, err
+		}
+		if pagination.Next == nil || pagination.Next.Offset <= offset {
+			return resp, err
+		}
+		offset = pagination.Next.Offset
+	}
+}
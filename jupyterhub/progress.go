@@ -0,0 +1,119 @@
+package jupyterhub
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ProgressEvent is one update on a server that's starting,
+// sent by the hub from the server's progress_url.
+type ProgressEvent struct {
+	Progress    int    `json:"progress"` // Percent complete.
+	Message     string `json:"message"`
+	HTMLMessage string `json:"html_message"`
+	Ready       bool   `json:"ready"`  // The server is up, this is the last event.
+	Failed      bool   `json:"failed"` // The server didn't start, this is the last event.
+	URL         string `json:"url"`    // Where the server is, once it's ready.
+}
+
+// WatchServerProgress follows the progress of username's default server starting,
+// calling f with each event as the hub sends it. It returns when the server
+// is ready, when it fails (with an error), when the hub ends the stream
+// or when f returns an error.
+func (conn Connection) WatchServerProgress(username string, f func(ProgressEvent) error) (resp *http.Response, err error) {
+	return conn.watchProgress(fmt.Sprintf("/users/%s/server/progress", username), f)
+}
+
+// WatchNamedServerProgress works as WatchServerProgress for named servers.
+func (conn Connection) WatchNamedServerProgress(username, servername string, f func(ProgressEvent) error) (resp *http.Response, err error) {
+	return conn.watchProgress(fmt.Sprintf("/users/%s/servers/%s/progress", username, servername), f)
+}
+
+// watchProgress reads the server-sent events stream at cmd.
+func (conn Connection) watchProgress(cmd string, f func(ProgressEvent) error) (resp *http.Response, err error) {
+	if err = conn.require(ServerProgress); err != nil {
+		return resp, err
+	}
+	client, err := conn.httpClient()
+	if err != nil {
+		return resp, err
+	}
+	conn.discover()
+	// The stream lasts as long as the spawn does, so the transport's
+	// timeout doesn't apply; the context still does.
+	streamClient := *client
+	streamClient.Timeout = 0
+
+	req := conn.newRequest(http.MethodGet, cmd, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	conn.logf(LogInfo, "Request: %s %s (event stream)", req.Method, req.URL)
+
+	resp, err = streamClient.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	defer resp.Body.Close()
+	if err = checkReturnCode(resp); err != nil {
+		return resp, err
+	}
+
+	err = readEvents(resp.Body, func(data []byte) error {
+		conn.logf(LogDebug, "Progress event: %s", string(data))
+		var ev ProgressEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return err
+		}
+		if err := f(ev); err != nil {
+			return err
+		}
+		switch {
+		case ev.Failed:
+			return fmt.Errorf("server failed to start: %s", ev.Message)
+		case ev.Ready:
+			return io.EOF
+		}
+		return nil
+	})
+	if err == io.EOF {
+		err = nil
+	}
+	return resp, err
+}
+
+// readEvents calls f with the data of each event in the server-sent events stream r,
+// until the stream ends or f returns an error. Comments (the hub's keep-alives),
+// event names and ids are ignored.
+func readEvents(r io.Reader, f func(data []byte) error) error {
+	var data bytes.Buffer
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				if err := f(data.Bytes()); err != nil {
+					return err
+				}
+				data.Reset()
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if data.Len() > 0 {
+		return f(data.Bytes())
+	}
+	return nil
+}
@@ -16,10 +16,10 @@ type Route struct {
 
 // RouteData is the for whom detail.
 type RouteData struct {
-	User         string `json:"user"`
-	ServerName   string `json:"server_name"`
-	Hub          bool   `json:"hub"`
-	LastActivity string `json:"last_activity"`
+	User         string    `json:"user"`
+	ServerName   string    `json:"server_name"`
+	Hub          bool      `json:"hub"`
+	LastActivity Timestamp `json:"last_activity"`
 }
 
 // GetProxy returns alist of routes maintained on the hub.
@@ -27,3 +27,23 @@ func (conn Connection) GetProxy() (routes Routes, resp *http.Response, err error
 	resp, err = conn.Get("/proxy", &routes)
 	return routes, resp, err
 }
+
+// ProxyUpdate tells the hub where to find the proxy's API, and the
+// token to use with it, e.g. after the proxy has been restarted elsewhere.
+// Empty fields are left as they are.
+type ProxyUpdate struct {
+	APIURL    string `json:"api_url,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// SyncProxy has the hub check the proxy's routing table against its own
+// and add or remove routes until they agree.
+func (conn Connection) SyncProxy() (resp *http.Response, err error) {
+	return conn.Post("/proxy", nil, nil)
+}
+
+// UpdateProxy points the hub at a new proxy API and/or auth token
+// and then syncs the proxy's routes.
+func (conn Connection) UpdateProxy(update ProxyUpdate) (resp *http.Response, err error) {
+	return conn.Patch("/proxy", update, nil)
+}
@@ -0,0 +1,110 @@
+package jupyterhub
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy describes how a connection retries failed requests.
+// Requests are retried after network errors and on 429 Too Many Requests,
+// 502 Bad Gateway, 503 Service Unavailable and 504 Gateway Timeout responses,
+// waiting an exponentially increasing, jittered, time between attempts.
+// A Retry-After header from the hub is used in place of the computed wait.
+//
+// POST and PATCH are not idempotent and are only retried when
+// RetryNonIdempotent is set.
+// The zero value never retries.
+type RetryPolicy struct {
+	MaxRetries         int           // Retries after the first attempt.
+	InitialBackoff     time.Duration // Wait before the first retry, doubled for each one after.
+	MaxBackoff         time.Duration // Limit on any single wait, including Retry-After. 0 is no limit.
+	RetryNonIdempotent bool          // Retry POST and PATCH as well.
+}
+
+// DefaultRetryPolicy rides out a hub restart or a short burst of rate limiting.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+}
+
+// backoff returns how long to wait before retry number attempt (starting at 0)
+// and whether the request should be retried at all.
+func (rp RetryPolicy) backoff(method string, attempt int, resp *http.Response, err error) (wait time.Duration, retry bool) {
+	if attempt >= rp.MaxRetries {
+		return 0, false
+	}
+	if !rp.RetryNonIdempotent && (method == http.MethodPost || method == http.MethodPatch) {
+		return 0, false
+	}
+
+	switch {
+	case resp == nil:
+		// No response, so a network error; but not if we gave up on purpose, or are offline.
+		if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrOffline) {
+			return 0, false
+		}
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable,
+		resp.StatusCode == http.StatusGatewayTimeout:
+		if after, ok := retryAfter(resp); ok {
+			return rp.limit(after), true
+		}
+	default:
+		return 0, false
+	}
+
+	// Exponential with "equal jitter": somewhere between half and all of the full backoff.
+	wait = rp.InitialBackoff << uint(attempt)
+	if wait <= 0 || (rp.MaxBackoff > 0 && wait > rp.MaxBackoff) {
+		wait = rp.MaxBackoff
+	}
+	if half := int64(wait / 2); half > 0 {
+		wait = time.Duration(half + rand.Int63n(half))
+	}
+	return wait, true
+}
+
+func (rp RetryPolicy) limit(wait time.Duration) time.Duration {
+	if rp.MaxBackoff > 0 && wait > rp.MaxBackoff {
+		wait = rp.MaxBackoff
+	}
+	return wait
+}
+
+// retryAfter parses the Retry-After header, which is either
+// a number of seconds or an HTTP date.
+func retryAfter(resp *http.Response) (wait time.Duration, ok bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if date, err := http.ParseTime(v); err == nil {
+		wait = time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// sleep waits for d, or until ctx is done in which case it returns ctx's error.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,144 @@
+package jupyterhub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jdrivas/sponde/jupyterhubtest"
+)
+
+// fastRetries retries quickly, so the tests don't wait.
+var fastRetries = RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+func TestRetryUnavailable(t *testing.T) {
+	hub, conn := newTestHub(t)
+	conn.Retry = fastRetries
+	hub.AddFault(jupyterhubtest.Fault{Path: "/users/alice", Status: http.StatusServiceUnavailable, Times: 2})
+
+	if _, _, err := conn.GetUser("alice"); err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if n := countRequests(hub, "GET /users/alice"); n != 3 {
+		t.Errorf("GetUser made %d requests, want 3", n)
+	}
+}
+
+func TestRetryDisconnect(t *testing.T) {
+	hub, conn := newTestHub(t)
+	conn.Retry = fastRetries
+	hub.AddFault(jupyterhubtest.Fault{Path: "/users/*", Disconnect: true, Times: 1})
+
+	if _, _, err := conn.GetUser("alice"); err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if n := countRequests(hub, "GET /users/alice"); n != 2 {
+		t.Errorf("GetUser made %d requests, want 2", n)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	hub, conn := newTestHub(t)
+	conn.Retry = fastRetries
+	hub.AddFault(jupyterhubtest.Fault{Path: "/users/alice", Status: http.StatusBadGateway})
+
+	_, resp, err := conn.GetUser("alice")
+	if err == nil || resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("GetUser = %v; want a 502 error", err)
+	}
+	if n := countRequests(hub, "GET /users/alice"); n != 4 {
+		t.Errorf("GetUser made %d requests, want 4", n)
+	}
+}
+
+func TestNoRetries(t *testing.T) {
+	tests := []struct {
+		name   string
+		fault  jupyterhubtest.Fault
+		policy RetryPolicy
+	}{
+		{"zero policy", jupyterhubtest.Fault{Status: http.StatusServiceUnavailable}, RetryPolicy{}},
+		{"not retryable", jupyterhubtest.Fault{Status: http.StatusInternalServerError}, fastRetries},
+		{"POST", jupyterhubtest.Fault{Method: http.MethodPost, Status: http.StatusServiceUnavailable}, fastRetries},
+	}
+	for _, tt := range tests {
+		hub, conn := newTestHub(t)
+		conn.Retry = tt.policy
+		hub.AddFault(tt.fault)
+		conn.CreateUser("carol", false)
+		conn.GetUser("alice")
+		if n := len(hub.Requests()); n != 2 {
+			t.Errorf("%s: made %d requests, want 2", tt.name, n)
+		}
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	hub, conn := newTestHub(t)
+	conn.Retry = fastRetries
+	conn.Retry.RetryNonIdempotent = true
+	hub.AddFault(jupyterhubtest.Fault{Method: http.MethodPost, Status: http.StatusTooManyRequests, RetryAfter: "0", Times: 1})
+
+	if _, _, err := conn.CreateUser("carol", false); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if n := countRequests(hub, "POST /users/carol"); n != 2 {
+		t.Errorf("CreateUser made %d requests, want 2", n)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.AddFault(jupyterhubtest.Fault{Delay: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := conn.WithContext(ctx).GetUser("alice"); err == nil {
+		t.Errorf("GetUser on a slow hub didn't time out")
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("GetUser took %v to time out", d)
+	}
+}
+
+func TestCanceledWhileWaitingToRetry(t *testing.T) {
+	hub, conn := newTestHub(t)
+	conn.Retry = RetryPolicy{MaxRetries: 3, InitialBackoff: time.Second}
+	hub.AddFault(jupyterhubtest.Fault{Status: http.StatusServiceUnavailable})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, resp, err := conn.WithContext(ctx).GetUser("alice")
+	if resp != nil {
+		t.Errorf("got the drained response of the failed attempt, want nil")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context's deadline", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	rp := RetryPolicy{MaxRetries: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	unavailable := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+	for attempt, max := range []time.Duration{100, 200, 300, 300, 300} {
+		max *= time.Millisecond
+		wait, retry := rp.backoff(http.MethodGet, attempt, unavailable, nil)
+		if !retry || wait < max/2 || wait > max {
+			t.Errorf("attempt %d: backoff = %v, %v; want between %v and %v", attempt, wait, retry, max/2, max)
+		}
+	}
+	if _, retry := rp.backoff(http.MethodGet, 5, unavailable, nil); retry {
+		t.Errorf("retried after MaxRetries")
+	}
+
+	unavailable.Header.Set("Retry-After", "2")
+	if wait, _ := rp.backoff(http.MethodGet, 0, unavailable, nil); wait != 300*time.Millisecond {
+		t.Errorf("Retry-After wait = %v, want MaxBackoff", wait)
+	}
+	if _, retry := rp.backoff(http.MethodGet, 0, nil, context.Canceled); retry {
+		t.Errorf("retried a canceled request")
+	}
+}
@@ -1,23 +1,82 @@
 package jupyterhub
 
-// GetInfo returns the Hub's system information.
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
 
 // Services is a list of Service
 type Services []Service
 
 // Service is the State the hub keeps on a hub managed process.
+// Roles, Display and OAuthClientID are only reported by JupyterHub 2+.
 type Service struct {
-	Name    string                 `json:"name"`
-	Admin   bool                   `json:"admin"`
-	URL     string                 `json:"url"`
-	Prefix  string                 `json:"prefix"`
-	PID     int                    `json:"pid"`
-	Command []string               `json:"command"`
-	Info    map[string]interface{} `json:"info"`
+	Kind          string                 `json:"kind"`
+	Name          string                 `json:"name"`
+	Admin         bool                   `json:"admin"`
+	Roles         []string               `json:"roles"`
+	URL           string                 `json:"url"`
+	Prefix        string                 `json:"prefix"`
+	PID           int                    `json:"pid"`
+	Command       []string               `json:"command"`
+	Info          map[string]interface{} `json:"info"`
+	Display       bool                   `json:"display"`
+	OAuthClientID string                 `json:"oauth_client_id"`
+	APIToken      string                 `json:"api_token"` // Only sent back when the service is created.
+	Scopes        []string               `json:"scopes"`    // Only from Whoami: the token's expanded scopes.
 }
 
-// GetServices lists the services on the Hub.
-func (conn Connection) GetServices() (services Services, err error) {
-	_, err = conn.Get("/services", services)
-	return services, err
+// ServiceRequest describes an externally managed service to add
+// to the hub with CreateService. Leave APIToken empty to have the
+// hub generate one.
+type ServiceRequest struct {
+	URL              string                 `json:"url,omitempty"`
+	Admin            bool                   `json:"admin,omitempty"`
+	OAuthClientID    string                 `json:"oauth_client_id,omitempty"`
+	OAuthRedirectURI string                 `json:"oauth_redirect_uri,omitempty"`
+	APIToken         string                 `json:"api_token,omitempty"`
+	Display          *bool                  `json:"display,omitempty"`
+	Info             map[string]interface{} `json:"info,omitempty"`
+}
+
+// GetServices lists the services on the Hub, sorted by name.
+func (conn Connection) GetServices() (services Services, resp *http.Response, err error) {
+	// The hub answers with an object of services keyed by name.
+	var byName map[string]Service
+	resp, err = conn.Get("/services", &byName)
+	for _, s := range byName {
+		services = append(services, s)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, resp, err
+}
+
+// GetService returns the service called name.
+func (conn Connection) GetService(name string) (service Service, resp *http.Response, err error) {
+	resp, err = conn.Get(fmt.Sprintf("/services/%s", name), &service)
+	return service, resp, err
+}
+
+// CreateService adds the service name to the hub, as described by newService,
+// and returns it. This needs a JupyterHub that can manage services
+// at runtime (5.1+). The returned service's APIToken is the only time
+// the hub will provide the token.
+func (conn Connection) CreateService(name string, newService ServiceRequest) (service Service, resp *http.Response, err error) {
+	if err = conn.require(ManagedServices); err != nil {
+		return service, resp, err
+	}
+	resp, err = conn.Post(fmt.Sprintf("/services/%s", name), newService, &service)
+	return service, resp, err
+}
+
+// DeleteService removes the service name from the hub. Only services
+// added with CreateService can be removed, those in the hub's
+// configuration file can't.
+func (conn Connection) DeleteService(name string) (resp *http.Response, err error) {
+	if err = conn.require(ManagedServices); err != nil {
+		return resp, err
+	}
+	resp, err = conn.Delete(fmt.Sprintf("/services/%s", name), nil, nil)
+	return resp, err
 }
@@ -0,0 +1,50 @@
+package jupyterhub
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestServices(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.AddService("configured", "http://127.0.0.1:9999", "", false)
+
+	s, resp, err := conn.CreateService("runtime", ServiceRequest{URL: "http://127.0.0.1:8889", Info: map[string]interface{}{"owner": "ops"}})
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("CreateService: %v", err)
+	}
+	if s.APIToken == "" || s.Prefix != "/services/runtime/" || !s.Display {
+		t.Errorf("CreateService = %+v", s)
+	}
+	svc := conn
+	svc.Token = s.APIToken
+	if _, _, err := conn.CreateService("runtime", ServiceRequest{}); !IsConflict(err) {
+		t.Errorf("CreateService of an existing service: err = %v, want 409", err)
+	}
+
+	services, _, err := conn.GetServices()
+	if err != nil || len(services) != 2 || services[0].Name != "configured" || services[1].Name != "runtime" {
+		t.Errorf("GetServices = %+v, %v", services, err)
+	}
+	s, _, err = conn.GetService("runtime")
+	if err != nil || s.Info["owner"] != "ops" || s.APIToken != "" {
+		t.Errorf("GetService = %+v, %v", s, err)
+	}
+
+	if owner, _, err := svc.Whoami(); err != nil || owner.Kind != ServiceKind || owner.Name() != "runtime" {
+		t.Errorf("Whoami with the service's token = %+v, %v", owner, err)
+	}
+
+	if _, err := conn.DeleteService("configured"); err == nil {
+		t.Errorf("DeleteService of a configured service didn't fail")
+	}
+	if _, err := conn.DeleteService("runtime"); err != nil {
+		t.Errorf("DeleteService: %v", err)
+	}
+	if _, _, err := conn.GetService("runtime"); !IsNotFound(err) {
+		t.Errorf("GetService of a deleted service: err = %v, want 404", err)
+	}
+	if _, _, err := svc.Whoami(); !IsForbidden(err) {
+		t.Errorf("Whoami with a deleted service's token: err = %v, want 403", err)
+	}
+}
@@ -0,0 +1,201 @@
+package jupyterhub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Shares is a list of Share.
+type Shares []Share
+
+// Share is access to a user's server granted to another user or to a group (JupyterHub 5+).
+// Kind says which one of User or Group is set.
+type Share struct {
+	Kind      string       `json:"kind"`
+	Server    SharedServer `json:"server"`
+	User      *NamedRef    `json:"user"`
+	Group     *NamedRef    `json:"group"`
+	Scopes    []string     `json:"scopes"`
+	CreatedAt Timestamp    `json:"created_at"`
+}
+
+// Age is how long ago the share was made, 0 if the hub didn't say.
+func (s Share) Age() time.Duration {
+	return since(s.CreatedAt)
+}
+
+// With is the name of the user or group the server is shared with.
+func (s Share) With() string {
+	switch {
+	case s.User != nil:
+		return s.User.Name
+	case s.Group != nil:
+		return s.Group.Name
+	}
+	return ""
+}
+
+// SharedServer is the server of a share.
+type SharedServer struct {
+	Name  string   `json:"name"`
+	User  NamedRef `json:"user"` // The owner.
+	URL   string   `json:"url"`
+	Ready bool     `json:"ready"`
+}
+
+// NamedRef is how the hub refers to a user or group inside of a share.
+type NamedRef struct {
+	Name string `json:"name"`
+}
+
+// ShareRequest names the user or group to share a server with (or revoke a share from).
+// Leave Scopes empty to share (or revoke) the default access:servers scope.
+type ShareRequest struct {
+	User   string   `json:"user,omitempty"`
+	Group  string   `json:"group,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// ShareCodes is a list of ShareCode.
+type ShareCodes []ShareCode
+
+// ShareCode is a code that grants a share to whoever accepts it (JupyterHub 5+).
+// Code and FullAcceptURL are only sent when the code is created.
+type ShareCode struct {
+	ID              string       `json:"id"`
+	Code            string       `json:"code"`
+	AcceptURL       string       `json:"accept_url"`
+	FullAcceptURL   string       `json:"full_accept_url"`
+	Server          SharedServer `json:"server"`
+	Scopes          []string     `json:"scopes"`
+	CreatedAt       Timestamp    `json:"created_at"`
+	ExpiresAt       Timestamp    `json:"expires_at"`
+	ExchangeCount   int          `json:"exchange_count"`
+	LastExchangedAt Timestamp    `json:"last_exchanged_at"`
+}
+
+// ExpiresIn is how long until the code expires, 0 if it has expired
+// or the hub didn't say.
+func (c ShareCode) ExpiresIn() time.Duration {
+	return until(c.ExpiresAt)
+}
+
+// sharePath is the shares (or share-codes) path for owner's server,
+// the default server's servername is "".
+func sharePath(prefix, owner, servername string) string {
+	return fmt.Sprintf("%s/%s/%s", prefix, owner, servername)
+}
+
+// GetShares returns the shares owner has granted on all of their servers.
+func (conn Connection) GetShares(owner string) (shares Shares, resp *http.Response, err error) {
+	return conn.getShares(fmt.Sprintf("/shares/%s", owner))
+}
+
+// GetServerShares returns the shares on one of owner's servers.
+func (conn Connection) GetServerShares(owner, servername string) (shares Shares, resp *http.Response, err error) {
+	return conn.getShares(sharePath("/shares", owner, servername))
+}
+
+// GetSharedWith returns the shares granted to username, i.e. the servers username can use.
+func (conn Connection) GetSharedWith(username string) (shares Shares, resp *http.Response, err error) {
+	return conn.getShares(fmt.Sprintf("/users/%s/shared", username))
+}
+
+// getShares collects every page of the list of shares at path.
+func (conn Connection) getShares(path string) (shares Shares, resp *http.Response, err error) {
+	if err = conn.require(ServerSharing); err != nil {
+		return shares, resp, err
+	}
+	resp, err = conn.eachPage(path, nil, DefaultPageSize, func(items json.RawMessage, p Pagination) error {
+		var page Shares
+		if err := json.Unmarshal(items, &page); err != nil {
+			return err
+		}
+		shares = append(shares, page...)
+		return nil
+	})
+	return shares, resp, err
+}
+
+// ShareServer shares owner's server with the user or group in with,
+// and returns the share.
+func (conn Connection) ShareServer(owner, servername string, with ShareRequest) (share Share, resp *http.Response, err error) {
+	if err = conn.require(ServerSharing); err != nil {
+		return share, resp, err
+	}
+	resp, err = conn.Post(sharePath("/shares", owner, servername), with, &share)
+	return share, resp, err
+}
+
+// RevokeShare takes the scopes in from (all of them if it has none) away from
+// the user or group in from, on owner's server.
+func (conn Connection) RevokeShare(owner, servername string, from ShareRequest) (share Share, resp *http.Response, err error) {
+	if err = conn.require(ServerSharing); err != nil {
+		return share, resp, err
+	}
+	resp, err = conn.Patch(sharePath("/shares", owner, servername), from, &share)
+	return share, resp, err
+}
+
+// RevokeAllShares revokes every share on owner's server.
+func (conn Connection) RevokeAllShares(owner, servername string) (resp *http.Response, err error) {
+	if err = conn.require(ServerSharing); err != nil {
+		return resp, err
+	}
+	return conn.Delete(sharePath("/shares", owner, servername), nil, nil)
+}
+
+// GetShareCodes returns the share codes for owner's server.
+// The codes themselves are not included.
+func (conn Connection) GetShareCodes(owner, servername string) (codes ShareCodes, resp *http.Response, err error) {
+	if err = conn.require(ServerSharing); err != nil {
+		return codes, resp, err
+	}
+	resp, err = conn.eachPage(sharePath("/share-codes", owner, servername), nil, DefaultPageSize, func(items json.RawMessage, p Pagination) error {
+		var page ShareCodes
+		if err := json.Unmarshal(items, &page); err != nil {
+			return err
+		}
+		codes = append(codes, page...)
+		return nil
+	})
+	return codes, resp, err
+}
+
+// CreateShareCode creates a code for owner's server, which expires in expiresIn
+// seconds (0 for the hub's default). The returned code is the only time the hub
+// will provide the Code and the FullAcceptURL.
+func (conn Connection) CreateShareCode(owner, servername string, expiresIn int) (code ShareCode, resp *http.Response, err error) {
+	if err = conn.require(ServerSharing); err != nil {
+		return code, resp, err
+	}
+	var content interface{}
+	if expiresIn > 0 {
+		content = struct {
+			ExpiresIn int `json:"expires_in"`
+		}{expiresIn}
+	}
+	resp, err = conn.Post(sharePath("/share-codes", owner, servername), content, &code)
+	return code, resp, err
+}
+
+// RevokeShareCode revokes the share code with id on owner's server.
+func (conn Connection) RevokeShareCode(owner, servername, id string) (resp *http.Response, err error) {
+	if err = conn.require(ServerSharing); err != nil {
+		return resp, err
+	}
+	q := url.Values{}
+	q.Set("id", id)
+	return conn.Delete(fmt.Sprintf("%s?%s", sharePath("/share-codes", owner, servername), q.Encode()), nil, nil)
+}
+
+// RevokeAllShareCodes revokes every share code for owner's server.
+func (conn Connection) RevokeAllShareCodes(owner, servername string) (resp *http.Response, err error) {
+	if err = conn.require(ServerSharing); err != nil {
+		return resp, err
+	}
+	return conn.Delete(sharePath("/share-codes", owner, servername), nil, nil)
+}
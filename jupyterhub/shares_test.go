@@ -0,0 +1,111 @@
+package jupyterhub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShares(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.StartServer("alice", "lab")
+	hub.AddGroup("class", "bob")
+
+	share, _, err := conn.ShareServer("alice", "lab", ShareRequest{User: "bob"})
+	if err != nil || share.Kind != "user" || share.With() != "bob" || share.Server.Name != "lab" || !share.Server.Ready {
+		t.Fatalf("ShareServer with bob = %+v, %v", share, err)
+	}
+	if len(share.Scopes) != 1 || share.Scopes[0] != "access:servers!server=alice/lab" || share.CreatedAt.IsZero() {
+		t.Errorf("ShareServer with bob: scopes %v, created %v", share.Scopes, share.CreatedAt)
+	}
+	read := "read:servers!server=alice/lab"
+	share, _, err = conn.ShareServer("alice", "lab", ShareRequest{Group: "class", Scopes: []string{"access:servers!server=alice/lab", read}})
+	if err != nil || share.Kind != "group" || share.With() != "class" || len(share.Scopes) != 2 {
+		t.Fatalf("ShareServer with class = %+v, %v", share, err)
+	}
+	if _, _, err := conn.ShareServer("alice", "lab", ShareRequest{User: "nobody"}); !IsBadRequest(err) {
+		t.Errorf("ShareServer with nobody: err = %v, want 400", err)
+	}
+	if _, _, err := conn.ShareServer("alice", "missing", ShareRequest{User: "bob"}); !IsNotFound(err) {
+		t.Errorf("ShareServer of a missing server: err = %v, want 404", err)
+	}
+
+	shares, _, err := conn.GetShares("alice")
+	if err != nil || len(shares) != 2 {
+		t.Errorf("GetShares = %d shares, %v; want 2", len(shares), err)
+	}
+	if shares, _, err := conn.GetServerShares("alice", ""); err != nil || len(shares) != 0 {
+		t.Errorf("GetServerShares of the default server = %d shares, %v; want none", len(shares), err)
+	}
+	if shared, _, err := conn.GetSharedWith("bob"); err != nil || len(shared) != 1 || shared[0].Server.User.Name != "alice" {
+		t.Errorf("GetSharedWith(bob) = %+v, %v", shared, err)
+	}
+
+	// Revoking some of the scopes leaves the rest.
+	share, _, err = conn.RevokeShare("alice", "lab", ShareRequest{Group: "class", Scopes: []string{read}})
+	if err != nil || len(share.Scopes) != 1 || share.Scopes[0] != "access:servers!server=alice/lab" {
+		t.Errorf("RevokeShare of read from class = %+v, %v", share, err)
+	}
+	// Revoking all of them removes the share.
+	if _, _, err := conn.RevokeShare("alice", "lab", ShareRequest{User: "bob"}); err != nil {
+		t.Errorf("RevokeShare from bob: %v", err)
+	}
+	shares, _, err = conn.GetServerShares("alice", "lab")
+	if err != nil || len(shares) != 1 || shares[0].With() != "class" {
+		t.Errorf("GetServerShares after revoking = %+v, %v; want class's share", shares, err)
+	}
+
+	if _, err := conn.RevokeAllShares("alice", "lab"); err != nil {
+		t.Errorf("RevokeAllShares: %v", err)
+	}
+	if shares, _, err := conn.GetShares("alice"); err != nil || len(shares) != 0 {
+		t.Errorf("GetShares after revoking all = %d shares, %v; want none", len(shares), err)
+	}
+}
+
+func TestShareCodes(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.StartServer("alice", "")
+
+	code, _, err := conn.CreateShareCode("alice", "", 3600)
+	if err != nil || code.ID == "" || code.Code == "" || code.FullAcceptURL == "" {
+		t.Fatalf("CreateShareCode = %+v, %v", code, err)
+	}
+	if in := code.ExpiresIn(); in < 59*time.Minute || in > time.Hour {
+		t.Errorf("CreateShareCode expires in %v, want about an hour", in)
+	}
+	other, _, err := conn.CreateShareCode("alice", "", 0)
+	if err != nil {
+		t.Fatalf("CreateShareCode: %v", err)
+	}
+
+	codes, _, err := conn.GetShareCodes("alice", "")
+	if err != nil || len(codes) != 2 || codes[0].ID != code.ID || codes[0].Code != "" {
+		t.Errorf("GetShareCodes = %+v, %v; want both codes, without the codes themselves", codes, err)
+	}
+
+	if _, err := conn.RevokeShareCode("alice", "", code.ID); err != nil {
+		t.Errorf("RevokeShareCode: %v", err)
+	}
+	codes, _, err = conn.GetShareCodes("alice", "")
+	if err != nil || len(codes) != 1 || codes[0].ID != other.ID {
+		t.Errorf("GetShareCodes after revoking one = %+v, %v; want just %s", codes, err, other.ID)
+	}
+
+	if _, err := conn.RevokeAllShareCodes("alice", ""); err != nil {
+		t.Errorf("RevokeAllShareCodes: %v", err)
+	}
+	if codes, _, err := conn.GetShareCodes("alice", ""); err != nil || len(codes) != 0 {
+		t.Errorf("GetShareCodes after revoking all = %d codes, %v; want none", len(codes), err)
+	}
+}
+
+func TestSharesNeedJupyterHub5(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.Version = "4.1.6"
+	if _, _, err := conn.ShareServer("alice", "", ShareRequest{User: "bob"}); !IsVersionError(err) {
+		t.Errorf("ShareServer on JupyterHub 4: err = %v, want a VersionError", err)
+	}
+	if n := countRequests(hub, "POST /shares/alice/"); n != 0 {
+		t.Errorf("the hub was asked to share %d times", n)
+	}
+}
@@ -0,0 +1,81 @@
+package jupyterhub
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Timestamp is a time sent by the hub. Time is the parsed time, which is
+// zero if the hub sent null (or something that couldn't be parsed),
+// and Raw is the time exactly as the hub sent it.
+type Timestamp struct {
+	Time time.Time
+	Raw  string
+}
+
+// The hub sends ISO 8601 times, in UTC. Older hubs leave off the zone.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+}
+
+// UnmarshalJSON decodes a JSON string or null.
+func (ts *Timestamp) UnmarshalJSON(b []byte) error {
+	*ts = Timestamp{}
+	if string(b) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(b, &ts.Raw); err != nil {
+		return err
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, ts.Raw); err == nil {
+			ts.Time = t
+			break
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the timestamp as the hub sent it, or null if it's empty.
+func (ts Timestamp) MarshalJSON() ([]byte, error) {
+	switch {
+	case ts.Raw != "":
+		return json.Marshal(ts.Raw)
+	case !ts.Time.IsZero():
+		return json.Marshal(ts.Time.Format(time.RFC3339Nano))
+	}
+	return []byte("null"), nil
+}
+
+// IsZero is true if there's no time.
+func (ts Timestamp) IsZero() bool {
+	return ts.Time.IsZero()
+}
+
+// String is the time as the hub sent it.
+func (ts Timestamp) String() string {
+	return ts.Raw
+}
+
+// since is how long ago ts was, 0 if there is no time.
+func since(ts Timestamp) time.Duration {
+	if ts.IsZero() {
+		return 0
+	}
+	if d := time.Since(ts.Time); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// until is how long until ts, 0 if there is no time or it has passed.
+func until(ts Timestamp) time.Duration {
+	if ts.IsZero() {
+		return 0
+	}
+	if d := time.Until(ts.Time); d > 0 {
+		return d
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package jupyterhub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestamp(t *testing.T) {
+	tests := []struct {
+		json string
+		want time.Time
+		raw  string
+	}{
+		{`null`, time.Time{}, ""},
+		{`""`, time.Time{}, ""},
+		{`"2023-04-05T06:07:08.123456Z"`, time.Date(2023, 4, 5, 6, 7, 8, 123456000, time.UTC), "2023-04-05T06:07:08.123456Z"},
+		{`"2019-01-02T03:04:05.678901"`, time.Date(2019, 1, 2, 3, 4, 5, 678901000, time.UTC), "2019-01-02T03:04:05.678901"},
+		{`"yesterday"`, time.Time{}, "yesterday"},
+	}
+	for _, tt := range tests {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(tt.json), &ts); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.json, err)
+			continue
+		}
+		if !ts.Time.Equal(tt.want) || ts.Raw != tt.raw {
+			t.Errorf("Unmarshal(%s) = %v, %q; want %v, %q", tt.json, ts.Time, ts.Raw, tt.want, tt.raw)
+		}
+		b, err := json.Marshal(ts)
+		want := tt.json
+		if want == `""` {
+			want = `null`
+		}
+		if err != nil || string(b) != want {
+			t.Errorf("Marshal(%s) = %s, %v", tt.json, b, err)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`12`), new(Timestamp)); err == nil {
+		t.Errorf("Unmarshal of a number didn't fail")
+	}
+}
+
+func TestIdleFor(t *testing.T) {
+	ago := func(d time.Duration) Timestamp {
+		return Timestamp{Time: time.Now().Add(-d)}
+	}
+	u := User{
+		LastActivity: ago(3 * time.Hour),
+		Servers: map[string]Server{
+			"":     {Started: ago(2 * time.Hour), LastActivity: ago(time.Hour)},
+			"long": {Started: ago(5 * time.Hour), LastActivity: ago(4 * time.Hour)},
+		},
+	}
+	if idle := u.IdleFor(); idle < time.Hour || idle > time.Hour+time.Minute {
+		t.Errorf("IdleFor = %v, want about an hour", idle)
+	}
+	if up := u.Uptime(); up < 5*time.Hour || up > 5*time.Hour+time.Minute {
+		t.Errorf("Uptime = %v, want about 5 hours", up)
+	}
+	if idle := (User{}).IdleFor(); idle != 0 {
+		t.Errorf("IdleFor without any activity = %v, want 0", idle)
+	}
+	if idle := (Server{LastActivity: ago(-time.Hour)}).IdleFor(); idle != 0 {
+		t.Errorf("IdleFor of activity in the future = %v, want 0", idle)
+	}
+}
+
+func TestShareCodeTimes(t *testing.T) {
+	expires := time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05.000000Z")
+	var c ShareCode
+	err := json.Unmarshal([]byte(`{"id": "sc_1", "created_at": "2024-05-06T07:08:09.000000Z",
+		"expires_at": "`+expires+`", "exchange_count": 0, "last_exchanged_at": null}`), &c)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if want := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC); !c.CreatedAt.Time.Equal(want) {
+		t.Errorf("CreatedAt = %v, want %v", c.CreatedAt.Time, want)
+	}
+	if !c.LastExchangedAt.IsZero() {
+		t.Errorf("LastExchangedAt = %v, want zero", c.LastExchangedAt)
+	}
+	if in := c.ExpiresIn(); in < 59*time.Minute || in > time.Hour {
+		t.Errorf("ExpiresIn = %v, want about an hour", in)
+	}
+	if in := (ShareCode{}).ExpiresIn(); in != 0 {
+		t.Errorf("ExpiresIn without an expiry = %v, want 0", in)
+	}
+}
@@ -0,0 +1,113 @@
+package jupyterhub
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Transport holds the HTTP settings used to reach a hub.
+// The zero value behaves like http.DefaultClient: system CA roots,
+// proxy from the environment and no timeout.
+type Transport struct {
+	CAFile             string        // PEM bundle of CAs to trust in addition to the system roots.
+	CertFile           string        // Client certificate, e.g. for hubs using internal_ssl.
+	KeyFile            string        // Key for the CertFile client certificate.
+	InsecureSkipVerify bool          // Don't verify the hub's certificate. Dangerous.
+	ProxyURL           string        // HTTP proxy to use instead of the environment's.
+	Timeout            time.Duration // Limit on the time for each request, 0 is no limit.
+}
+
+// Warnings is where warnings too important to leave to a connection's Logger,
+// like disabled TLS certificate verification, are written as well.
+// Set it to nil if the Logger is enough, e.g. because it also writes to stderr.
+var Warnings io.Writer = os.Stderr
+
+// Clients are built once for each distinct Transport and then reused,
+// so that connections keep their pools of open connections across calls.
+// Hubs are warned about once each, whichever client they use.
+var (
+	clientsMu sync.Mutex
+	clients   = make(map[Transport]*http.Client)
+	warned    = make(map[string]bool) // Keyed by HubURL.
+)
+
+// httpClient returns the client configured by the connection's Transport.
+func (conn Connection) httpClient() (*http.Client, error) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	if conn.Transport.InsecureSkipVerify && !warned[conn.HubURL] {
+		warned[conn.HubURL] = true
+		msg := fmt.Sprintf("TLS certificate verification is DISABLED for %s. "+
+			"The hub's identity is not being checked and your token can be intercepted.", conn.HubURL)
+		conn.logf(LogWarn, "%s", msg)
+		if Warnings != nil {
+			fmt.Fprintf(Warnings, "WARNING: %s\n", msg)
+		}
+	}
+
+	if client, ok := clients[conn.Transport]; ok {
+		return client, nil
+	}
+	client, err := conn.Transport.newClient()
+	if err == nil {
+		clients[conn.Transport] = client
+	}
+	return client, err
+}
+
+// newClient builds an http.Client from the transport settings.
+func (tr Transport) newClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if tr.ProxyURL != "" {
+		proxyURL, err := url.Parse(tr.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("bad proxy URL \"%s\": %v", tr.ProxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{}
+	if tr.CAFile != "" {
+		pem, err := ioutil.ReadFile(tr.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read CA file: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file \"%s\"", tr.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if tr.CertFile != "" || tr.KeyFile != "" {
+		if tr.CertFile == "" || tr.KeyFile == "" {
+			return nil, fmt.Errorf("a client certificate needs both a cert file and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(tr.CertFile, tr.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	tlsConfig.InsecureSkipVerify = tr.InsecureSkipVerify
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   tr.Timeout,
+	}, nil
+}
@@ -1,8 +1,13 @@
 package jupyterhub
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
 // UserList is a a collection of users.
@@ -16,9 +21,11 @@ type User struct {
 	Groups       []string          `json:"groups"`
 	ServerURL    string            `json:"server"`
 	Pending      string            `json:"pending"`
-	Created      string            `json:"created"`
-	LastActivity string            `json:"last_activity"`
+	Created      Timestamp         `json:"created"`
+	LastActivity Timestamp         `json:"last_activity"`
 	Servers      map[string]Server `json:"servers"`
+	Roles        []string          `json:"roles"`  // JupyterHub 2+
+	Scopes       []string          `json:"scopes"` // JupyterHub 2+, only from Whoami: the token's expanded scopes.
 }
 
 // Server is the data for a Notebook server a user is running.
@@ -28,11 +35,17 @@ type Server struct {
 	Pending      string      `json:"pending"`
 	URL          string      `json:"url"`
 	ProgressURL  string      `json:"progress_url"`
-	Started      string      `json:"started"`
-	LastActivity string      `json:"last_activity"`
+	Started      Timestamp   `json:"started"`
+	LastActivity Timestamp   `json:"last_activity"`
 	State        StateValues `json:"state"`
+	UserOptions  UserOptions `json:"user_options"`
 }
 
+// UserOptions are the spawner options a server is started with,
+// such as a KubeSpawner profile or an image. What's understood
+// depends on the hub's spawner.
+type UserOptions map[string]interface{}
+
 // StateValues are returned from the sever.
 type StateValues struct {
 	PodName string `json:"pod_name"`
@@ -46,44 +59,251 @@ type UpdatedUser struct {
 
 // GetUser retruns a users information
 func (conn Connection) GetUser(username string) (user User, resp *http.Response, err error) {
-	resp, err = conn.Get(fmt.Sprintf("%s%s", "/users", username), &user)
+	resp, err = conn.Get(fmt.Sprintf("/users/%s", username), &user)
 	return user, resp, err
 }
 
-// GetUsers gets users details from the hub.
-// It returns a list of users for those that are found
-// on the hub,  list of usernamess that were not found,
-// and an errorif there we any problems.
-// TODO: This make one call for each user. This is inefficient for
-// hubs with a small number of users, but probably more efficent for hubs
-// with a large numbr of users. Decide if this should change.
-// TODO: Depdneing on the previous TODO, note that only the last calls
-// http.Response is returned, this is indicative of needing a better solution (perhaps
-// move this logic into a CMD function that is used rather than here in JH.)
-func (conn Connection) GetUsers(usernames []string) (users UserList, badUsers []string, resp *http.Response, err error) {
-	for _, un := range usernames {
-		user := new(User)
-		resp, err = conn.Get(fmt.Sprintf("%s%s", "/users/", un), user)
-		if err == nil {
-			users = append(users, *user)
-		} else {
-			if resp.StatusCode == http.StatusNotFound {
-				badUsers = append(badUsers, un)
-				err = nil
-			} else {
-				break
-			}
+// ErrServerNotFound is returned by GetServer when the user doesn't have the server.
+var ErrServerNotFound = errors.New("server not found")
+
+// GetServer returns the details of one of username's servers, servername "" is the default server.
+// The hub only reports servers that are running or pending, so the error is
+// ErrServerNotFound for one that's stopped, as well as for one that doesn't exist.
+func (conn Connection) GetServer(username, servername string) (server Server, resp *http.Response, err error) {
+	user, resp, err := conn.GetUser(username)
+	if err != nil {
+		return server, resp, err
+	}
+	server, ok := user.Servers[servername]
+	if !ok {
+		name := servername
+		if name == "" {
+			name = "default"
+		}
+		err = fmt.Errorf("%w: user %s has no %s server running", ErrServerNotFound, username, name)
+	}
+	return server, resp, err
+}
+
+// UserResult is the outcome of looking up one user in GetUsers.
+type UserResult struct {
+	BatchResult
+	User User
+}
+
+// UserResults are the results of GetUsers, in the order the names were given.
+type UserResults []UserResult
+
+// Users returns the users that were found.
+func (rs UserResults) Users() (users UserList) {
+	for _, r := range rs {
+		if r.Err == nil {
+			users = append(users, r.User)
 		}
 	}
-	return users, badUsers, resp, err
+	return users
+}
+
+// NotFound returns the names of the users the hub doesn't have.
+func (rs UserResults) NotFound() (names []string) {
+	for _, r := range rs {
+		if IsNotFound(r.Err) {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
+
+// Failed returns the lookups that failed for some reason other than
+// the user not being on the hub.
+func (rs UserResults) Failed() (failed BatchResults) {
+	for _, r := range rs {
+		if r.Err != nil && !IsNotFound(r.Err) {
+			failed = append(failed, r.BatchResult)
+		}
+	}
+	return failed
+}
+
+// GetUsers gets the details of each of the users from the hub, making
+// up to conn.Concurrency requests at once. Every name gets a result,
+// whether or not the lookups of the others worked.
+func (conn Connection) GetUsers(usernames []string) (results UserResults) {
+	results = make(UserResults, len(usernames))
+	conn.batch(len(usernames), func(i int) {
+		user, resp, err := conn.GetUser(usernames[i])
+		results[i] = UserResult{BatchResult{Name: usernames[i], Resp: resp, Err: err}, user}
+	})
+	return results
 }
 
 // GetAllUsers returns a list of logged in JupyterHub users.
+// Users are fetched a page of DefaultPageSize at a time,
+// see EachUsersPage to process them as they arrive instead.
 func (conn Connection) GetAllUsers() (users UserList, resp *http.Response, err error) {
-	resp, err = conn.Get("/users", &users)
+	resp, err = conn.EachUsersPage(DefaultPageSize, func(page UserList, _ Pagination) error {
+		users = append(users, page...)
+		return nil
+	})
+	return users, resp, err
+}
+
+// EachUsersPage gets the hub's users pageSize at a time, calling f with each page
+// as it arrives. It stops early if f returns an error, and returns that error.
+func (conn Connection) EachUsersPage(pageSize int, f func(UserList, Pagination) error) (resp *http.Response, err error) {
+	return conn.EachUsersPageByState(AnyState, pageSize, f)
+}
+
+// UserState selects users by the state of their servers.
+type UserState string
+
+// User states understood by the hub.
+const (
+	AnyState      UserState = ""         // All users.
+	ActiveState   UserState = "active"   // Users with a server running or pending.
+	InactiveState UserState = "inactive" // Users with no servers running or pending.
+	ReadyState    UserState = "ready"    // Users with a server that's ready.
+)
+
+// ParseUserState returns the UserState named by s, or an error
+// if there isn't one.
+func ParseUserState(s string) (UserState, error) {
+	switch state := UserState(s); state {
+	case AnyState, ActiveState, InactiveState, ReadyState:
+		return state, nil
+	}
+	return AnyState, fmt.Errorf("unknown user state \"%s\"; try one of: %s, %s, %s", s, ActiveState, InactiveState, ReadyState)
+}
+
+// Matches is true if the user u is in state, judged from its servers.
+func (state UserState) Matches(u User) bool {
+	switch state {
+	case ActiveState:
+		return u.active()
+	case InactiveState:
+		return !u.active()
+	case ReadyState:
+		for _, s := range u.Servers {
+			if s.Ready {
+				return true
+			}
+		}
+		// Hubs without named servers only report the default server's URL.
+		return len(u.Servers) == 0 && u.ServerURL != "" && u.Pending == ""
+	}
+	return true
+}
+
+func (u User) active() bool {
+	return len(u.Servers) > 0 || u.ServerURL != "" || u.Pending != ""
+}
+
+// Uptime is how long the server has been running, 0 if the hub didn't say when it started.
+func (s Server) Uptime() time.Duration {
+	return since(s.Started)
+}
+
+// IdleFor is how long it's been since the server was last active,
+// 0 if the hub doesn't know when that was.
+func (s Server) IdleFor() time.Duration {
+	return since(s.LastActivity)
+}
+
+// Uptime is how long the user's longest running server has been running,
+// 0 if they have no servers running.
+func (u User) Uptime() (uptime time.Duration) {
+	for _, s := range u.Servers {
+		if d := s.Uptime(); d > uptime {
+			uptime = d
+		}
+	}
+	return uptime
+}
+
+// IdleFor is how long it's been since the user, or any of their servers,
+// was last active, 0 if the hub doesn't know when that was.
+func (u User) IdleFor() time.Duration {
+	last := u.LastActivity
+	for _, s := range u.Servers {
+		if s.LastActivity.Time.After(last.Time) {
+			last = s.LastActivity
+		}
+	}
+	return since(last)
+}
+
+// GetAllUsersByState returns the hub's users that are in state, see EachUsersPageByState.
+func (conn Connection) GetAllUsersByState(state UserState) (users UserList, resp *http.Response, err error) {
+	resp, err = conn.EachUsersPageByState(state, DefaultPageSize, func(page UserList, _ Pagination) error {
+		users = append(users, page...)
+		return nil
+	})
+	return users, resp, err
+}
+
+// EachUsersPageByState works as EachUsersPage, but only for users in state.
+// The hub is asked to do the filtering, but hubs before 2.0 ignore the state
+// parameter, and some reject it, so each page is also filtered here with
+// UserState.Matches. If the hub rejects the parameter all users are fetched
+// instead. Pages may then hold fewer than pageSize users.
+func (conn Connection) EachUsersPageByState(state UserState, pageSize int, f func(UserList, Pagination) error) (resp *http.Response, err error) {
+	var query url.Values
+	if state != AnyState {
+		query = url.Values{"state": {string(state)}}
+	}
+
+	started := false
+	matching := func(items json.RawMessage, p Pagination) error {
+		started = true
+		var users, matched UserList
+		if err := json.Unmarshal(items, &users); err != nil {
+			return err
+		}
+		for _, u := range users {
+			if state.Matches(u) {
+				matched = append(matched, u)
+			}
+		}
+		return f(matched, p)
+	}
+	resp, err = conn.eachPage("/users", query, pageSize, matching)
+
+	if state != AnyState && !started && IsBadRequest(err) {
+		conn.logf(LogInfo, "Hub rejected the state filter (%v), filtering users locally.", err)
+		resp, err = conn.eachPage("/users", nil, pageSize, matching)
+	}
+	return resp, err
+}
+
+// NewUsers is the request to create many users at once.
+type NewUsers struct {
+	UserNames []string `json:"usernames"`
+	Admin     bool     `json:"admin"`
+}
+
+// CreateUser creates a user named username on the hub, as an admin if admin is set.
+// It's an error (see IsConflict) if the user already exists.
+func (conn Connection) CreateUser(username string, admin bool) (user User, resp *http.Response, err error) {
+	newUser := struct {
+		Admin bool `json:"admin"`
+	}{admin}
+	resp, err = conn.Post(fmt.Sprintf("/users/%s", username), newUser, &user)
+	return user, resp, err
+}
+
+// CreateUsers creates all of the users named in usernames that don't already
+// exist, and returns those that were created.
+// It's an error (see IsConflict) if all of them already exist.
+func (conn Connection) CreateUsers(usernames []string, admin bool) (users UserList, resp *http.Response, err error) {
+	resp, err = conn.Post("/users", NewUsers{UserNames: usernames, Admin: admin}, &users)
 	return users, resp, err
 }
 
+// DeleteUser deletes the user named username from the hub.
+func (conn Connection) DeleteUser(username string) (resp *http.Response, err error) {
+	return conn.Delete(fmt.Sprintf("/users/%s", username), nil, nil)
+}
+
 // UpdateUser changes a users name or admin status. Use the UpdatedUser object to specify and you only need
 // to fill in the values that are changing, though it all works with a full object.
 func (conn Connection) UpdateUser(name string, user UpdatedUser) (returnUser UpdatedUser, resp *http.Response, err error) {
@@ -93,34 +313,70 @@ func (conn Connection) UpdateUser(name string, user UpdatedUser) (returnUser Upd
 
 // Servers
 
-// StartServer will attempt to start the named users server. Started will return true if
+// StartServer will attempt to start the named users server with the spawner options
+// provided, which may be nil. Started will return true if
 // the serer is now started, or fase if start has been requested but not yet started.
 // As usual if something goes wrong, err != nil.
-func (conn Connection) StartServer(username string) (started bool, resp *http.Response, err error) {
-	return conn.startNotebookServer(fmt.Sprintf("/users/%s/servers", username))
+func (conn Connection) StartServer(username string, options UserOptions) (started bool, resp *http.Response, err error) {
+	return conn.startNotebookServer(fmt.Sprintf("/users/%s/server", username), options)
 }
 
 // StopServer will attempt to stop the named users server. Stp[[ed]] will return true if
 // the serer is now stopped, or false if start has been requested but not yet started.
 // As usual if something goes wrong, err != nil.
 func (conn Connection) StopServer(username string) (stopped bool, resp *http.Response, err error) {
-	return conn.stopNotebookServer(fmt.Sprintf("/users/%s/servers", username))
+	return conn.stopNotebookServer(fmt.Sprintf("/users/%s/server", username), nil)
+}
+
+// StartServers starts the default server of each of the users, as StartServer
+// does, making up to conn.Concurrency requests at once.
+// The hub answers 201 Created for a server that has started
+// and 202 Accepted for one that is still starting.
+func (conn Connection) StartServers(usernames []string, options UserOptions) BatchResults {
+	return conn.batchNames(usernames, func(username string) (*http.Response, error) {
+		_, resp, err := conn.StartServer(username, options)
+		return resp, err
+	})
+}
+
+// StopServers stops the default server of each of the users, as StopServer
+// does, making up to conn.Concurrency requests at once.
+// The hub answers 204 No Content for a server that has stopped
+// and 202 Accepted for one that is still stopping.
+func (conn Connection) StopServers(usernames []string) BatchResults {
+	return conn.batchNames(usernames, func(username string) (*http.Response, error) {
+		_, resp, err := conn.StopServer(username)
+		return resp, err
+	})
 }
 
 // StartNamedServer works as StartServer for named servers. Servers are identified by a  user name and servername.
-func (conn Connection) StartNamedServer(username, servername string) (started bool, resp *http.Response, err error) {
-	return conn.startNotebookServer(fmt.Sprintf("/users/%s/servers/%s", username, servername))
+func (conn Connection) StartNamedServer(username, servername string, options UserOptions) (started bool, resp *http.Response, err error) {
+	return conn.startNotebookServer(fmt.Sprintf("/users/%s/servers/%s", username, servername), options)
 }
 
 // StopNamedServer works as StopServer for named servers. Servers are identified by a user name and servername.
 func (conn Connection) StopNamedServer(username, servername string) (started bool, resp *http.Response, err error) {
-	return conn.stopNotebookServer(fmt.Sprintf("/users/%s/servers/%s", username, servername))
+	return conn.stopNotebookServer(fmt.Sprintf("/users/%s/servers/%s", username, servername), nil)
 }
 
 // StartNteookbServer implements the logic for the two starts above taking the full command
 // for either named server or just the default server for a user.
-func (conn Connection) startNotebookServer(cmd string) (started bool, resp *http.Response, err error) {
-	resp, err = conn.Post(cmd, nil, nil)
+// The options, if any, are sent as the request body.
+func (conn Connection) startNotebookServer(cmd string, options UserOptions) (started bool, resp *http.Response, err error) {
+	var content interface{}
+	if len(options) > 0 {
+		content = options
+	}
+	resp, err = conn.Post(cmd, content, nil)
+	if IsNamedServerLimit(err) {
+		var hubErr *HubError
+		errors.As(err, &hubErr)
+		hubErr.Hint = "The hub's named_server_limit_per_user has been reached, delete a named server to make room."
+	}
+	if err != nil {
+		return started, resp, err
+	}
 
 	// This is probably overkill.
 	// But captures the expected behavior
@@ -130,25 +386,44 @@ func (conn Connection) startNotebookServer(cmd string) (started bool, resp *http
 	case http.StatusAccepted:
 		started = false
 	default:
-		if err == nil {
-			err = fmt.Errorf("StartServer = got neither 201 Created, nor 202 Accepted, nor an error. I don't think your server started")
-		}
+		err = fmt.Errorf("StartServer = got neither 201 Created, nor 202 Accepted, nor an error. I don't think your server started")
 	}
 	return started, resp, err
 }
 
-// StoptNteookbServer implements the logic for the two starts above taking the full command
-func (conn Connection) stopNotebookServer(cmd string) (stopped bool, resp *http.Response, err error) {
-	resp, err = conn.Delete(cmd, nil, nil)
+// DeleteNamedServer stops the user's named server, if it's running, and then removes it
+// altogether, freeing a place under the hub's named_server_limit_per_user.
+// Deleted is true if the server is gone now, or false if the delete is pending.
+func (conn Connection) DeleteNamedServer(username, servername string) (deleted bool, resp *http.Response, err error) {
+	remove := struct {
+		Remove bool `json:"remove"`
+	}{true}
+	return conn.stopNotebookServer(fmt.Sprintf("/users/%s/servers/%s", username, servername), remove)
+}
+
+// IsNamedServerLimit is true if err is the hub refusing to create a named server
+// because the user already has named_server_limit_per_user of them.
+func IsNamedServerLimit(err error) bool {
+	var hubErr *HubError
+	return errors.As(err, &hubErr) && hubErr.StatusCode == http.StatusBadRequest &&
+		strings.Contains(hubErr.Message, "already has the maximum")
+}
+
+// StoptNteookbServer implements the logic for the two starts above taking the full command,
+// and content (which may be nil) for the body.
+func (conn Connection) stopNotebookServer(cmd string, content interface{}) (stopped bool, resp *http.Response, err error) {
+	resp, err = conn.Delete(cmd, content, nil)
+	if err != nil {
+		return stopped, resp, err
+	}
+
 	switch resp.StatusCode {
 	case http.StatusNoContent:
 		stopped = true
 	case http.StatusAccepted:
 		stopped = false
 	default:
-		if err == nil {
-			err = fmt.Errorf("StartServer = got neither 204 NoContent, nor 202 Accepted, nor an error. I don't think your server may not be stopping")
-		}
+		err = fmt.Errorf("StopServer = got neither 204 NoContent, nor 202 Accepted, nor an error. I don't think your server may not be stopping")
 	}
 
 	return stopped, resp, err
@@ -166,29 +441,45 @@ type Tokens struct {
 }
 
 // APIToken is server data for a user owned API token.
+// Scopes and Roles are only reported by JupyterHub 2+.
 type APIToken struct {
-	Kind         string `json:"kind"`
-	ID           string `json:"id"`
-	User         string `json:"user"`
-	Service      string `json:"service"`
-	Note         string `json:"note"`
-	Created      string `json:"created"`
-	Expires      string `json:"expires"`
-	LastActivity string `json:"last_activity"`
-	Token        string `json:"token"`
+	Kind         string    `json:"kind"`
+	ID           string    `json:"id"`
+	User         string    `json:"user"`
+	Service      string    `json:"service"`
+	Note         string    `json:"note"`
+	Scopes       []string  `json:"scopes"`
+	Roles        []string  `json:"roles"`
+	Created      Timestamp `json:"created"`
+	Expires      Timestamp `json:"expires_at"`
+	LastActivity Timestamp `json:"last_activity"`
+	Token        string    `json:"token"`
 }
 
 // OAuthToken is the server data for a user associated OAuth credentialed token.
+// Scopes and Roles are only reported by JupyterHub 2+.
 type OAuthToken struct {
-	Kind         string `json:"kind"`
-	ID           string `json:"id"`
-	User         string `json:"user"`
-	Service      string `json:"service"`
-	Note         string `json:"note"`
-	Created      string `json:"created"`
-	Expires      string `json:"expires"`
-	LastActivity string `json:"last_activity"`
-	OAuthClient  string `json:"oauth_client"`
+	Kind         string    `json:"kind"`
+	ID           string    `json:"id"`
+	User         string    `json:"user"`
+	Service      string    `json:"service"`
+	Note         string    `json:"note"`
+	Scopes       []string  `json:"scopes"`
+	Roles        []string  `json:"roles"`
+	Created      Timestamp `json:"created"`
+	Expires      Timestamp `json:"expires_at"`
+	LastActivity Timestamp `json:"last_activity"`
+	OAuthClient  string    `json:"oauth_client"`
+}
+
+// TokenRequest is what can be asked for in a new API token.
+// Leave Scopes and Roles empty to get the hub's default permissions,
+// and ExpiresIn 0 for a token that doesn't expire.
+type TokenRequest struct {
+	Note      string   `json:"note,omitempty"`
+	ExpiresIn int      `json:"expires_in,omitempty"` // Seconds
+	Scopes    []string `json:"scopes,omitempty"`     // JupyterHub 2+
+	Roles     []string `json:"roles,omitempty"`      // JupyterHub 2+, use Scopes for 3+
 }
 
 // GetTokens returns all of the tokens for the specified users
@@ -203,10 +494,15 @@ func (conn Connection) GetToken(username, tokenID string) (token APIToken, resp
 	return token, resp, err
 }
 
-// CreateToken will create a single APIToken from the Template provided,
+// CreateToken will create a single APIToken as described by newToken,
 // for the user and return the newly created token.
-// Only Note will be saved in the new token.
-func (conn Connection) CreateToken(username string, newToken APIToken) (createdToken APIToken, resp *http.Response, err error) {
+// The returned token is the only time the hub will provide the Token itself.
+func (conn Connection) CreateToken(username string, newToken TokenRequest) (createdToken APIToken, resp *http.Response, err error) {
+	if len(newToken.Scopes) > 0 || len(newToken.Roles) > 0 {
+		if err = conn.require(RolesAndScopes); err != nil {
+			return createdToken, resp, err
+		}
+	}
 	resp, err = conn.Post(fmt.Sprintf("/users/%s/tokens", username), newToken, &createdToken)
 	return createdToken, resp, err
 }
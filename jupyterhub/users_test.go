@@ -0,0 +1,295 @@
+package jupyterhub
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUsers(t *testing.T) {
+	_, conn := newTestHub(t)
+
+	if _, _, err := conn.CreateUser("carol", true); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, _, err := conn.CreateUser("carol", false); !IsConflict(err) {
+		t.Errorf("CreateUser of an existing user: err = %v, want 409", err)
+	}
+	users, resp, err := conn.CreateUsers([]string{"dave", "erin"}, false)
+	if err != nil || resp.StatusCode != http.StatusCreated || len(users) != 2 {
+		t.Errorf("CreateUsers = %d users, %v", len(users), err)
+	}
+
+	u, _, err := conn.GetUser("carol")
+	if err != nil || !u.Admin || u.Kind != UserKind {
+		t.Errorf("GetUser(carol) = %+v, %v", u, err)
+	}
+	if _, _, err := conn.GetUser("nobody"); !IsNotFound(err) {
+		t.Errorf("GetUser(nobody): err = %v, want 404", err)
+	}
+
+	results := conn.GetUsers([]string{"alice", "nobody", "bob"})
+	found, missing := results.Users(), results.NotFound()
+	if len(found) != 2 || len(missing) != 1 || missing[0] != "nobody" || len(results.Failed()) != 0 {
+		t.Errorf("GetUsers = %d found, missing %v, failed %v", len(found), missing, results.Failed())
+	}
+
+	updated, _, err := conn.UpdateUser("erin", UpdatedUser{Name: "frank", Admin: true})
+	if err != nil || updated.Name != "frank" || !updated.Admin {
+		t.Errorf("UpdateUser = %+v, %v", updated, err)
+	}
+
+	resp, err = conn.DeleteUser("frank")
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DeleteUser: %v", err)
+	}
+	if _, err := conn.DeleteUser("frank"); !IsNotFound(err) {
+		t.Errorf("DeleteUser of a deleted user: err = %v, want 404", err)
+	}
+
+	all, _, err := conn.GetAllUsers()
+	if err != nil {
+		t.Fatalf("GetAllUsers: %v", err)
+	}
+	var names []string
+	for _, u := range all {
+		names = append(names, u.Name)
+	}
+	want := []string{"admin", "alice", "bob", "carol", "dave"}
+	if len(names) != len(want) {
+		t.Fatalf("GetAllUsers = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("GetAllUsers = %v, want %v", names, want)
+		}
+	}
+}
+
+func TestEachUsersPage(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.AddUser("carol", false)
+	hub.AddUser("dave", false)
+
+	var pages, users int
+	var last Pagination
+	_, err := conn.EachUsersPage(2, func(page UserList, p Pagination) error {
+		pages++
+		users += len(page)
+		last = p
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachUsersPage: %v", err)
+	}
+	if pages != 3 || users != 5 || last.Total != 5 || last.Next != nil {
+		t.Errorf("EachUsersPage = %d pages of %d users, last %+v; want 3 pages of 5", pages, users, last)
+	}
+
+	stop := errors.New("stop")
+	pages = 0
+	_, err = conn.EachUsersPage(2, func(UserList, Pagination) error {
+		pages++
+		return stop
+	})
+	if err != stop || pages != 1 {
+		t.Errorf("EachUsersPage stopping early = %d pages, %v", pages, err)
+	}
+}
+
+func TestGetAllUsersByState(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.StartServer("alice", "")
+
+	tests := []struct {
+		state UserState
+		want  int
+	}{
+		{AnyState, 3},
+		{ActiveState, 1},
+		{InactiveState, 2},
+		{ReadyState, 1},
+	}
+	for _, tt := range tests {
+		users, _, err := conn.GetAllUsersByState(tt.state)
+		if err != nil || len(users) != tt.want {
+			t.Errorf("GetAllUsersByState(%q) = %d users, %v; want %d", tt.state, len(users), err, tt.want)
+		}
+	}
+}
+
+func TestGetAllUsersByStateOldHub(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.Version = "1.5.0"
+	hub.StartServer("alice", "")
+
+	users, _, err := conn.GetAllUsersByState(ActiveState)
+	if err != nil || len(users) != 1 || users[0].Name != "alice" {
+		t.Errorf("GetAllUsersByState(active) on a hub that ignores state = %d users, %v; want alice", len(users), err)
+	}
+}
+
+func TestNonAdmin(t *testing.T) {
+	_, conn := newTestHub(t)
+	conn.Token = "alice-token"
+
+	if _, _, err := conn.GetUser("alice"); err != nil {
+		t.Errorf("alice getting herself: %v", err)
+	}
+	if _, _, err := conn.GetUser("bob"); !IsForbidden(err) {
+		t.Errorf("alice getting bob: err = %v, want 403", err)
+	}
+	if _, _, err := conn.GetAllUsers(); !IsForbidden(err) {
+		t.Errorf("alice listing users: err = %v, want 403", err)
+	}
+	if _, _, err := conn.UpdateUser("alice", UpdatedUser{Name: "alice", Admin: true}); !IsForbidden(err) {
+		t.Errorf("alice making herself an admin: err = %v, want 403", err)
+	}
+	if _, _, err := conn.StartServer("alice", nil); err != nil {
+		t.Errorf("alice starting her server: %v", err)
+	}
+}
+
+func TestServers(t *testing.T) {
+	hub, conn := newTestHub(t)
+
+	started, resp, err := conn.StartServer("alice", UserOptions{"profile": "small"})
+	if err != nil || !started || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("StartServer = %v, %v", started, err)
+	}
+	s, _, err := conn.GetServer("alice", "")
+	if err != nil || !s.Ready || s.UserOptions["profile"] != "small" {
+		t.Errorf("GetServer = %+v, %v", s, err)
+	}
+	if _, _, err := conn.StartServer("alice", nil); !IsBadRequest(err) {
+		t.Errorf("StartServer of a running server: err = %v, want 400", err)
+	}
+
+	stopped, _, err := conn.StopServer("alice")
+	if err != nil || !stopped {
+		t.Errorf("StopServer = %v, %v", stopped, err)
+	}
+	if _, _, err := conn.GetServer("alice", ""); !errors.Is(err, ErrServerNotFound) {
+		t.Errorf("GetServer of a stopped server: err = %v, want ErrServerNotFound", err)
+	}
+
+	hub.NamedServerLimit = 1
+	if _, _, err := conn.StartNamedServer("alice", "one", nil); err != nil {
+		t.Errorf("StartNamedServer: %v", err)
+	}
+	if _, _, err := conn.StartNamedServer("alice", "two", nil); !IsNamedServerLimit(err) {
+		t.Errorf("StartNamedServer over the limit: err = %v, want the named server limit", err)
+	}
+	if deleted, _, err := conn.DeleteNamedServer("alice", "one"); err != nil || !deleted {
+		t.Errorf("DeleteNamedServer = %v, %v", deleted, err)
+	}
+	if _, _, err := conn.StartNamedServer("alice", "two", nil); err != nil {
+		t.Errorf("StartNamedServer after a delete: %v", err)
+	}
+	if _, _, err := conn.StopNamedServer("alice", "nothing"); !IsNotFound(err) {
+		t.Errorf("StopNamedServer of a server that doesn't exist: err = %v, want 404", err)
+	}
+}
+
+func TestSlowServers(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.SpawnDelay = 50 * time.Millisecond
+	hub.StopDelay = 50 * time.Millisecond
+
+	started, resp, err := conn.StartServer("alice", nil)
+	if err != nil || started || resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("StartServer = %v, %v; want pending", started, err)
+	}
+	u, _, _ := conn.GetUser("alice")
+	if u.Pending != "spawn" {
+		t.Errorf("pending = %q, want spawn", u.Pending)
+	}
+
+	var events []ProgressEvent
+	_, err = conn.WatchServerProgress("alice", func(e ProgressEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WatchServerProgress: %v", err)
+	}
+	last := events[len(events)-1]
+	if len(events) < 2 || !last.Ready || last.Progress != 100 || last.URL != "/user/alice/" {
+		t.Errorf("WatchServerProgress = %d events, ending with %+v", len(events), last)
+	}
+
+	stopped, _, err := conn.StopServer("alice")
+	if err != nil || stopped {
+		t.Errorf("StopServer = %v, %v; want pending", stopped, err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, _, err := conn.GetServer("alice", ""); !errors.Is(err, ErrServerNotFound) {
+		t.Errorf("GetServer after stopping: err = %v, want ErrServerNotFound", err)
+	}
+}
+
+func TestFailedSpawn(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.SpawnDelay = 20 * time.Millisecond
+	hub.SpawnFailure = "out of memory"
+
+	if _, _, err := conn.StartNamedServer("alice", "big", nil); err != nil {
+		t.Fatalf("StartNamedServer: %v", err)
+	}
+	_, err := conn.WatchNamedServerProgress("alice", "big", func(ProgressEvent) error { return nil })
+	if err == nil {
+		t.Errorf("WatchNamedServerProgress of a failed spawn didn't fail")
+	}
+}
+
+func TestTokens(t *testing.T) {
+	_, conn := newTestHub(t)
+
+	created, resp, err := conn.CreateToken("alice", TokenRequest{Note: "test", Scopes: []string{"read:users"}})
+	if err != nil || resp.StatusCode != http.StatusCreated || created.Token == "" {
+		t.Fatalf("CreateToken = %+v, %v", created, err)
+	}
+
+	// The new token works.
+	alice := conn
+	alice.Token = created.Token
+	if owner, _, err := alice.Whoami(); err != nil || owner.Name() != "alice" {
+		t.Errorf("Whoami with the new token = %s, %v", owner.Name(), err)
+	}
+
+	tokens, _, err := conn.GetTokens("alice")
+	if err != nil || len(tokens.APITokens) != 1 || tokens.APITokens[0].Note != "test" {
+		t.Errorf("GetTokens = %+v, %v", tokens, err)
+	}
+	token, _, err := conn.GetToken("alice", created.ID)
+	if err != nil || token.Token != "" {
+		t.Errorf("GetToken = %+v, %v; the token itself shouldn't be sent again", token, err)
+	}
+
+	if _, err := conn.DeleteToken("alice", created.ID); err != nil {
+		t.Errorf("DeleteToken: %v", err)
+	}
+	if _, _, err := conn.GetToken("alice", created.ID); !IsNotFound(err) {
+		t.Errorf("GetToken of a deleted token: err = %v, want 404", err)
+	}
+	if _, _, err := alice.Whoami(); !IsForbidden(err) {
+		t.Errorf("Whoami with a deleted token: err = %v, want 403", err)
+	}
+}
+
+func TestReportActivity(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.StartServer("alice", "")
+
+	if _, err := conn.ReportActivityNow("alice", ""); err != nil {
+		t.Errorf("ReportActivityNow: %v", err)
+	}
+	if _, err := conn.ReportActivityNow("alice", "missing"); !IsBadRequest(err) {
+		t.Errorf("ReportActivityNow for a server that isn't running: err = %v, want 400", err)
+	}
+	u, _, _ := conn.GetUser("alice")
+	if u.LastActivity.IsZero() {
+		t.Errorf("no last activity for alice")
+	}
+}
@@ -2,29 +2,39 @@ package jupyterhub
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
-	"os"
-	// "strings"
-
-	t "github.com/jdrivas/sponde/term"
-	"github.com/spf13/viper"
-)
-
-var (
-	// Note: I don't expect that there are any dependencies that
-	// should cause this to be a problem.
-	hubClient = http.DefaultClient
 )
 
 //
 // Public API
 //
 
+// WithContext returns a copy of the connection that makes all of its requests with ctx.
+// Every call on the returned Connection (Send, Get, GetAllUsers, StartServer etc.) will
+// be abandoned when ctx is canceled or its deadline expires.
+func (conn Connection) WithContext(ctx context.Context) Connection {
+	if ctx == nil {
+		panic("nil context")
+	}
+	conn.ctx = ctx
+	return conn
+}
+
+// Context returns the connection's context. If no context has been set
+// with WithContext, this is context.Background().
+func (conn Connection) Context() context.Context {
+	if conn.ctx != nil {
+		return conn.ctx
+	}
+	return context.Background()
+}
+
 // Send performs an HTTP request on the URL with the Token in the connection, using
 // the HTTP provided by method.
 // If content is non-nil, it's marshalled into the body  as a json string.
@@ -32,29 +42,72 @@ var (
 // If result is non-nil Send umarshalls the response body,
 // aasumed to be JSON encoded, into the result object passed in.
 // If result is a []map[string]interface{}, you'll get a map of the JSON object.
+// Failed requests are retried according to the connection's Retry policy.
 func (conn Connection) Send(method, cmd string, content interface{}, result interface{}) (resp *http.Response, err error) {
+	return conn.send(method, cmd, nil, content, result)
+}
+
+// send is Send with header added to the request headers.
+func (conn Connection) send(method, cmd string, header http.Header, content interface{}, result interface{}) (resp *http.Response, err error) {
 
-	//  No content, jsut send.
-	if content == nil {
-		req := conn.newRequest(method, cmd, nil)
-		resp, err = sendReq(req, result)
-	} else {
-		// Otherwise, marshal the object and send the request.
-		var b []byte
-		switch c := content.(type) {
-		case string:
-			// If we use unmarshall on the string, it escapges the quotes: "foo" => \"foo\".
-			b = []byte(c)
-		default:
-			b, err = json.Marshal(c)
+	if conn.Offline && method != http.MethodGet {
+		return resp, fmt.Errorf("%w: can't %s %s without the hub", ErrOffline, method, cmd)
+	}
+	client, err := conn.httpClient()
+	if err != nil {
+		return resp, err
+	}
+	conn.discover()
+
+	// Marshal the content once, so we can resend it on a retry.
+	var b []byte
+	switch c := content.(type) {
+	case nil:
+		//  No content, jsut send.
+	case string:
+		// If we use unmarshall on the string, it escapges the quotes: "foo" => \"foo\".
+		b = []byte(c)
+	default:
+		b, err = json.Marshal(c)
+		if err != nil {
+			return resp, err
 		}
-		if err == nil {
-			buff := bytes.NewBuffer(b)
-			req := conn.newRequest(method, cmd, buff)
+	}
+
+	for attempt := 0; ; attempt++ {
+		var req *http.Request
+		if content == nil {
+			req = conn.newRequest(method, cmd, nil)
+		} else {
+			req = conn.newRequest(method, cmd, bytes.NewBuffer(b))
 			req.Header.Add("Content-Type", "application/json")
-			resp, err = sendReq(req, result)
+		}
+		for k, vs := range header {
+			for _, v := range vs {
+				req.Header.Add(k, v)
+			}
+		}
+		resp, err = conn.sendReq(client, req, result)
+
+		wait, retry := conn.Retry.backoff(method, attempt, resp, err)
+		if !retry {
+			break
+		}
+		conn.logf(LogInfo, "Retrying %s %s in %s (%d of %d): %v", method, req.URL, wait, attempt+1, conn.Retry.MaxRetries, err)
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if sleepErr := sleep(conn.Context(), wait); sleepErr != nil {
+			// The last response has been thrown away, there's nothing left to return.
+			return nil, fmt.Errorf("%s %s abandoned waiting to retry after: %v: %w", method, req.URL, err, sleepErr)
 		}
 	}
+
+	// Anything but a GET that the hub carried out may have changed what the cache holds.
+	if method != http.MethodGet && resp != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		conn.invalidateCache(resp.Request)
+	}
 	return resp, err
 }
 
@@ -73,6 +126,11 @@ func (conn Connection) Delete(cmd string, content, result interface{}) (resp *ht
 	return conn.Send(http.MethodDelete, cmd, content, result)
 }
 
+// Put works like Send using the PUT verb.
+func (conn Connection) Put(cmd string, content, result interface{}) (resp *http.Response, err error) {
+	return conn.Send(http.MethodPut, cmd, content, result)
+}
+
 // Patch works like Send using the Patch verb.
 func (conn Connection) Patch(cmd string, content, result interface{}) (resp *http.Response, err error) {
 	return conn.Send(http.MethodPatch, cmd, content, result)
@@ -82,57 +140,69 @@ func (conn Connection) Patch(cmd string, content, result interface{}) (resp *htt
 // Private API
 //
 
-// sendReq sends along the request with some logging along the way.
-func sendReq(req *http.Request, result interface{}) (resp *http.Response, err error) {
+// sendReq sends along the request on client with some logging along the way.
+func (conn Connection) sendReq(client *http.Client, req *http.Request, result interface{}) (resp *http.Response, err error) {
 
 	switch {
-	// TODO: This wil dump the authorization token. Which it probably shouldn't.
-	case debug():
+	case conn.logEnabled(LogDebug):
 		reqDump, dumpErr := httputil.DumpRequestOut(req, true)
 		reqStr := string(reqDump)
 		if dumpErr != nil {
-			fmt.Printf("Error dumping request (display as generic object): %v\n", dumpErr)
+			conn.logf(LogDebug, "Error dumping request (display as generic object): %v", dumpErr)
 			reqStr = fmt.Sprintf("%v", req)
 		}
-		fmt.Printf("%s %s\n", t.Title("Request"), t.Text(reqStr))
-		fmt.Println()
-	case verbose():
-		fmt.Printf("%s %s\n", t.Title("Request:"), t.Text("%s %s", req.Method, req.URL))
-		fmt.Println()
+		conn.logf(LogDebug, "Request:\n%s", reqStr)
+	case conn.logEnabled(LogInfo):
+		conn.logf(LogInfo, "Request: %s %s", req.Method, req.URL)
 	}
 
-	resp, err = hubClient.Do(req)
+	cached, fromCache := conn.fromCache(req)
+	switch {
+	case fromCache:
+		conn.logf(LogInfo, "Cached: %s %s", req.Method, req.URL)
+		resp = cached
+	case conn.Offline:
+		err = fmt.Errorf("%w: no cached response for %s %s", ErrOffline, req.Method, req.URL)
+	default:
+		resp, err = client.Do(req)
+	}
 	if err == nil {
+		conn.versionFromHeader(resp)
 
-		if debug() {
+		if conn.logEnabled(LogDebug) {
 			respDump, dumpErr := httputil.DumpResponse(resp, true)
 			respStr := string(respDump)
 			if dumpErr != nil {
-				fmt.Printf("Error dumping response (display as generic object): %v\n", dumpErr)
+				conn.logf(LogDebug, "Error dumping response (display as generic object): %v", dumpErr)
 				respStr = fmt.Sprintf("%v", resp)
 			}
-			fmt.Printf("%s\n%s\n", t.Title("Respose:"), t.Text(respStr))
-			fmt.Println()
+			conn.logf(LogDebug, "Response:\n%s", respStr)
 		}
 
 		// Do this after the Dump, the dump reads out the response for reprting and
 		// replaces the reader with anotherone that has the data.
-		err = checkReturnCode(*resp)
+		err = checkReturnCode(resp)
+		if err == nil && !fromCache {
+			conn.toCache(req, resp)
+		}
 		if result != nil {
 			if err == nil {
-				err = unmarshal(resp, result)
+				err = conn.unmarshal(resp, result)
 			}
 		}
 
 	}
+	if err != nil {
+		conn.logf(LogInfo, "Failed: %s %s: %v", req.Method, req.URL, err)
+	}
 	return resp, err
 }
 
-// newRequest creates a request as usual prepending the connections HubURL to the cmd,
-// and adding the Authorization header using token.
+// newRequest creates a request as usual prepending the URL of the hub's API to the cmd,
+// and adding the Authorization header using token. The request carries the connection's context.
 func (conn Connection) newRequest(method, cmd string, body io.Reader) *http.Request {
 	// req, err := conn.jhReq(method, cmd, body)
-	req, err := http.NewRequest(method, conn.HubURL+cmd, body)
+	req, err := http.NewRequestWithContext(conn.Context(), method, conn.apiURL()+cmd, body)
 	if err != nil {
 		panic(fmt.Sprintf("Coulnd't generate HTTP request - %s\n", err.Error()))
 	}
@@ -144,71 +214,25 @@ func (conn Connection) newRequest(method, cmd string, body io.Reader) *http.Requ
 
 // This eats the body in the response, but returns the body in
 //  obj passed in. They must match of course.
-func unmarshal(resp *http.Response, obj interface{}) (err error) {
+func (conn Connection) unmarshal(resp *http.Response, obj interface{}) (err error) {
 	var body []byte
 	body, err = ioutil.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err == nil {
 
-		if debug() {
-			// TODO: Printf-ing the output of json.Indent through the bytes.Buffer.String
-			// produces cruft. However writting directly to it, works o.k.
-			// prettyJSON := bytes.Buffer{}
+		if conn.logEnabled(LogDebug) {
 			var prettyJSON bytes.Buffer
-			fmt.Fprintf(&prettyJSON, t.Title("Pretty print response body:\n"))
 			indentErr := json.Indent(&prettyJSON, body, "", " ")
 			if indentErr == nil {
-				// fmt.Printf("%s %s\n", t.Title("Response body is:"), t.Text("%s\n", prettyJSON))
-				prettyJSON.WriteTo(os.Stdout)
-				fmt.Println()
-				fmt.Println()
+				conn.logf(LogDebug, "Pretty print response body:\n%s", prettyJSON.String())
 			} else {
-				fmt.Printf("%s\n", t.Fail("Error indenting JSON - %s", indentErr.Error()))
-				fmt.Printf("%s %s\n", t.Title("Body:"), t.Text(string(body)))
+				conn.logf(LogDebug, "Error indenting JSON - %s", indentErr.Error())
+				conn.logf(LogDebug, "Body: %s", string(body))
 			}
 		}
 
 		json.Unmarshal(body, &obj)
-		if debug() {
-			fmt.Printf("%s %s\n", t.Title("Unmarshaled object: "), t.Text("%#v", obj))
-			fmt.Println()
-		}
-	}
-	return err
-}
-
-// Returns an "informative" error if not 200
-func checkReturnCode(resp http.Response) (err error) {
-	err = nil
-	if resp.StatusCode >= 300 {
-		switch resp.StatusCode {
-		case http.StatusNotFound:
-			err = httpErrorMesg(resp, "Check for valid argument (user, group etc).")
-		case http.StatusUnauthorized:
-			err = httpErrorMesg(resp, "Check for valid token.")
-		case http.StatusForbidden:
-			err = httpErrorMesg(resp, "Check for valid token and token user must be an admin")
-		default:
-			err = httpError(resp)
-		}
+		conn.logf(LogDebug, "Unmarshaled object: %#v", obj)
 	}
 	return err
 }
-
-func httpErrorMesg(resp http.Response, message string) error {
-	return fmt.Errorf("HTTP Request %s:%s, HTTP Response: %s. %s",
-		resp.Request.Method, resp.Request.URL, resp.Status, message)
-}
-
-func httpError(resp http.Response) error {
-	return httpErrorMesg(resp, "")
-}
-
-// TODO: replace this with proper logging ASAP.
-func debug() bool {
-	return viper.GetBool("debug")
-}
-
-func verbose() bool {
-	return viper.GetBool("verbose")
-}
@@ -1,6 +1,12 @@
 package jupyterhub
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
 
 // Version is the version nof the running JupyterHub
 type Version struct {
@@ -8,7 +14,205 @@ type Version struct {
 }
 
 // GetVersion returns the version of the JupyterHub from querying JupyterHub API.
+// The version is remembered for HubVersion.
 func (conn Connection) GetVersion() (version Version, resp *http.Response, err error) {
 	resp, err = conn.Get("/", &version)
+	if err == nil {
+		conn.rememberVersion(version.Version)
+	}
 	return version, resp, err
 }
+
+// HubVersion is a JupyterHub release, e.g. 4.1.6.
+type HubVersion struct {
+	Major, Minor, Patch int
+}
+
+// ParseVersion parses a JupyterHub version string. Pre-release and
+// development suffixes, as in "5.0.0b2" or "4.1.0.dev", are ignored.
+func ParseVersion(s string) (v HubVersion, err error) {
+	fields := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".", 3)
+	parts := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, f := range fields {
+		end := strings.IndexFunc(f, func(r rune) bool { return r < '0' || r > '9' })
+		if end < 0 {
+			end = len(f)
+		}
+		if end == 0 {
+			if i == 0 {
+				return v, fmt.Errorf("can't parse JupyterHub version \"%s\"", s)
+			}
+			break
+		}
+		*parts[i], _ = strconv.Atoi(f[:end])
+		if end < len(f) {
+			// A suffix, nothing after it counts.
+			break
+		}
+	}
+	return v, nil
+}
+
+func (v HubVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast is true if v is min or later.
+func (v HubVersion) AtLeast(min HubVersion) bool {
+	if v.Major != min.Major {
+		return v.Major > min.Major
+	}
+	if v.Minor != min.Minor {
+		return v.Minor > min.Minor
+	}
+	return v.Patch >= min.Patch
+}
+
+// Feature is part of the hub's API that only some versions of JupyterHub have.
+type Feature struct {
+	Name  string
+	Since HubVersion
+}
+
+// Features that calls check the hub's version for.
+var (
+	ServerProgress  = Feature{"Server progress", HubVersion{0, 9, 0}}
+	RolesAndScopes  = Feature{"Roles and scopes", HubVersion{2, 0, 0}}
+	GroupProperties = Feature{"Group properties", HubVersion{3, 0, 0}}
+	ServerSharing   = Feature{"Server sharing", HubVersion{5, 0, 0}}
+	ManagedServices = Feature{"Creating and deleting services", HubVersion{5, 1, 0}}
+)
+
+// hubs remembers what's been learned about each hub, keyed by the HubURL
+// it's reached with, so that it's shared by every connection to the hub.
+var hubs = struct {
+	sync.Mutex
+	versions map[string]HubVersion
+	apiURLs  map[string]string
+}{
+	versions: make(map[string]HubVersion),
+	apiURLs:  make(map[string]string),
+}
+
+// ForgetHub forgets the version and API URL found for the hub at hubURL,
+// so they're found again, e.g. after the hub has been upgraded.
+func ForgetHub(hubURL string) {
+	hubs.Lock()
+	defer hubs.Unlock()
+	delete(hubs.versions, hubURL)
+	delete(hubs.apiURLs, hubURL)
+}
+
+// rememberVersion keeps the hub's version, if it can be parsed.
+func (conn Connection) rememberVersion(s string) {
+	v, err := ParseVersion(s)
+	if err != nil {
+		return
+	}
+	hubs.Lock()
+	defer hubs.Unlock()
+	hubs.versions[conn.HubURL] = v
+}
+
+// HubVersion returns the version of the hub. The hub is only asked if the
+// version isn't already known, from an earlier call or from the version
+// header the hub sends with every response.
+func (conn Connection) HubVersion() (v HubVersion, err error) {
+	// Finding the API finds the version too.
+	conn.discover()
+	hubs.Lock()
+	v, ok := hubs.versions[conn.HubURL]
+	hubs.Unlock()
+	if ok {
+		return v, nil
+	}
+	version, _, err := conn.GetVersion()
+	if err != nil {
+		return v, err
+	}
+	return ParseVersion(version.Version)
+}
+
+// Supports is true if the hub's version has the feature f.
+func (conn Connection) Supports(f Feature) (bool, error) {
+	v, err := conn.HubVersion()
+	if err != nil {
+		return false, err
+	}
+	return v.AtLeast(f.Since), nil
+}
+
+// require returns a VersionError if the hub is too old for f. If the hub's
+// version can't be found the request is left to succeed or fail on its own.
+func (conn Connection) require(f Feature) error {
+	v, err := conn.HubVersion()
+	if err != nil || v.AtLeast(f.Since) {
+		return nil
+	}
+	return &VersionError{Feature: f, Version: v}
+}
+
+// APIPrefix is where JupyterHub serves its REST API, below its base URL.
+const APIPrefix = "/hub/api"
+
+// apiURL is the URL of the hub's REST API: HubURL, or what discover found from it.
+func (conn Connection) apiURL() string {
+	hubs.Lock()
+	defer hubs.Unlock()
+	if u, ok := hubs.apiURLs[conn.HubURL]; ok {
+		return u
+	}
+	return strings.TrimSuffix(conn.HubURL, "/")
+}
+
+// discover finds the hub's REST API when HubURL is the hub's own URL,
+// e.g. https://hub.example.com, rather than that of its API. If the hub
+// answers with its version at APIPrefix below HubURL, that's the API.
+// HubURLs ending in /api are taken to be the API already and aren't checked.
+func (conn Connection) discover() {
+	base := strings.TrimSuffix(conn.HubURL, "/")
+	if base == "" || strings.HasSuffix(base, "/api") {
+		return
+	}
+	hubs.Lock()
+	_, ok := hubs.apiURLs[conn.HubURL]
+	hubs.Unlock()
+	if ok {
+		return
+	}
+
+	client, err := conn.httpClient()
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequestWithContext(conn.Context(), http.MethodGet, base+APIPrefix+"/", nil)
+	if err != nil {
+		return
+	}
+	var version Version
+	resp, err := conn.sendReq(client, req, &version)
+	if resp == nil {
+		// The hub isn't there, try again next time.
+		return
+	}
+
+	apiURL := base
+	if err == nil && version.Version != "" {
+		apiURL = base + APIPrefix
+		conn.rememberVersion(version.Version)
+	}
+	conn.logf(LogInfo, "Using the hub API at %s", apiURL)
+	hubs.Lock()
+	defer hubs.Unlock()
+	hubs.apiURLs[conn.HubURL] = apiURL
+}
+
+// versionHeader is sent by the hub with each API response.
+const versionHeader = "X-JupyterHub-Version"
+
+// versionFromHeader remembers the version in the response's header, if there is one.
+func (conn Connection) versionFromHeader(resp *http.Response) {
+	if v := resp.Header.Get(versionHeader); v != "" {
+		conn.rememberVersion(v)
+	}
+}
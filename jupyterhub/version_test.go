@@ -0,0 +1,130 @@
+package jupyterhub
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want HubVersion
+		ok   bool
+	}{
+		{"4.1.6", HubVersion{4, 1, 6}, true},
+		{"5.0.0b2", HubVersion{5, 0, 0}, true},
+		{"4.1.0.dev", HubVersion{4, 1, 0}, true},
+		{"1.2", HubVersion{1, 2, 0}, true},
+		{"v3.1.1", HubVersion{3, 1, 1}, true},
+		{"0.9.6\n", HubVersion{0, 9, 6}, true},
+		{"5.1rc1", HubVersion{5, 1, 0}, true},
+		{"", HubVersion{}, false},
+		{"unknown", HubVersion{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	v := HubVersion{4, 1, 6}
+	for _, min := range []HubVersion{{4, 1, 6}, {4, 1, 0}, {4, 0, 9}, {3, 9, 9}} {
+		if !v.AtLeast(min) {
+			t.Errorf("%v.AtLeast(%v) = false", v, min)
+		}
+	}
+	for _, min := range []HubVersion{{4, 1, 7}, {4, 2, 0}, {5, 0, 0}} {
+		if v.AtLeast(min) {
+			t.Errorf("%v.AtLeast(%v) = true", v, min)
+		}
+	}
+}
+
+func TestHubVersion(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.Version = "4.1.6"
+
+	// The version comes along with any response.
+	if _, _, err := conn.GetUser("alice"); err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	v, err := conn.HubVersion()
+	if err != nil || v != (HubVersion{4, 1, 6}) {
+		t.Errorf("HubVersion = %v, %v; want 4.1.6", v, err)
+	}
+	if n := countRequests(hub, "GET /"); n != 0 {
+		t.Errorf("HubVersion asked the hub %d times, want 0", n)
+	}
+
+	ForgetHub(conn.HubURL)
+	if _, err := conn.HubVersion(); err != nil {
+		t.Errorf("HubVersion: %v", err)
+	}
+	if _, err := conn.HubVersion(); err != nil {
+		t.Errorf("HubVersion: %v", err)
+	}
+	if n := countRequests(hub, "GET /"); n != 1 {
+		t.Errorf("HubVersion asked the hub %d times, want 1", n)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.Version = "4.1.6"
+
+	_, _, err := conn.ShareServer("alice", "", ShareRequest{User: "bob"})
+	if !IsVersionError(err) || !strings.Contains(err.Error(), "requires JupyterHub >= 5.0.0") {
+		t.Errorf("ShareServer on 4.1.6: err = %v, want a version error", err)
+	}
+	if _, _, err := conn.CreateService("new", ServiceRequest{}); !IsVersionError(err) {
+		t.Errorf("CreateService on 4.1.6: err = %v, want a version error", err)
+	}
+	for _, r := range hub.Requests() {
+		if strings.HasPrefix(r, "POST") {
+			t.Errorf("the hub was asked to %s", r)
+		}
+	}
+
+	hub.AddGroup("students")
+	if _, _, err := conn.SetGroupProperties("students", map[string]interface{}{"a": 1}); err != nil {
+		t.Errorf("SetGroupProperties on 4.1.6: %v", err)
+	}
+	if ok, err := conn.Supports(ServerSharing); ok || err != nil {
+		t.Errorf("Supports(ServerSharing) = %v, %v on 4.1.6", ok, err)
+	}
+}
+
+func TestRequireUnknownVersion(t *testing.T) {
+	hub, conn := newTestHub(t)
+	hub.Version = "not a version"
+	hub.AddGroup("students")
+
+	// Without a version, the hub gets to decide.
+	if _, _, err := conn.SetGroupProperties("students", map[string]interface{}{"a": 1}); err != nil {
+		t.Errorf("SetGroupProperties: %v", err)
+	}
+}
+
+func TestDiscoverAPI(t *testing.T) {
+	hub, conn := newTestHub(t)
+	conn.HubURL = strings.TrimSuffix(conn.HubURL, APIPrefix) + "/"
+
+	if _, _, err := conn.GetUser("alice"); err != nil {
+		t.Fatalf("GetUser with the hub's base URL: %v", err)
+	}
+	if _, _, err := conn.GetUser("bob"); err != nil {
+		t.Fatalf("GetUser with the hub's base URL: %v", err)
+	}
+	if n := countRequests(hub, "GET /"); n != 1 {
+		t.Errorf("looked for the API %d times, want 1", n)
+	}
+	if _, err := conn.HubVersion(); err != nil {
+		t.Errorf("HubVersion: %v", err)
+	}
+	if n := countRequests(hub, "GET /"); n != 1 {
+		t.Errorf("HubVersion asked the hub again after finding the API")
+	}
+}
@@ -0,0 +1,63 @@
+// Command fakehub runs an in-memory JupyterHub, seeded with a few users,
+// groups and services, for trying sponde without a real hub:
+//
+//	go run ./jupyterhubtest/fakehub -spawn-delay 5s
+//	sponde --hub-url http://127.0.0.1:8081 --token jupyterhubtest-admin-token list users
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jdrivas/sponde/jupyterhubtest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8081", "listen on this address")
+	version := flag.String("version", jupyterhubtest.DefaultVersion, "the JupyterHub version to report")
+	spawnDelay := flag.Duration("spawn-delay", 3*time.Second, "how long servers take to start")
+	stopDelay := flag.Duration("stop-delay", time.Second, "how long servers take to stop")
+	spawnFailure := flag.String("spawn-failure", "", "make every spawn fail with this message")
+	namedServers := flag.Int("named-server-limit", 0, "named servers allowed for each user, 0 is no limit")
+	flag.Parse()
+
+	hub := jupyterhubtest.NewHub()
+	hub.Version = *version
+	hub.SpawnDelay = *spawnDelay
+	hub.StopDelay = *stopDelay
+	hub.SpawnFailure = *spawnFailure
+	hub.NamedServerLimit = *namedServers
+	seed(hub)
+
+	fmt.Printf("Fake JupyterHub %s listening on http://%s\n\n", hub.Version, *addr)
+	fmt.Printf("  sponde --hub-url http://%s --token %s list users\n\n", *addr, jupyterhubtest.AdminToken)
+	fmt.Printf("alice's token is %q, alice isn't an admin.\n", "alice-token")
+	if err := http.ListenAndServe(*addr, logRequests(hub)); err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
+
+// seed gives the hub something to look at.
+func seed(hub *jupyterhubtest.Hub) {
+	hub.AddUser("alice", false)
+	hub.AddUserToken("alice", "alice-token")
+	hub.AddUser("bob", false)
+	hub.AddUser("carol", true)
+	hub.AddGroup("students", "alice", "bob")
+	hub.AddGroup("staff", "carol")
+	hub.StartServer("alice", "")
+	hub.StartServer("carol", "analysis")
+	hub.AddService("announcement", "http://127.0.0.1:8889", "announcement-token", false)
+}
+
+func logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL)
+		h.ServeHTTP(w, r)
+	})
+}
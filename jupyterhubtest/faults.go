@@ -0,0 +1,98 @@
+package jupyterhubtest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fault makes the hub misbehave on matching requests, to test how
+// clients handle errors, retries, slow hubs and dropped connections.
+type Fault struct {
+	Method string // Only requests with this method, "" for any method.
+	Path   string // Only requests for this path (relative to APIPrefix), a trailing "*" matches any path with that prefix. "" for any path.
+
+	Status     int           // Fail with this HTTP status.
+	Message    string        // The hub's message for the failure, the status text if "".
+	RetryAfter string        // Send this Retry-After header with the failure.
+	Delay      time.Duration // Wait this long before answering, or until the request is canceled.
+	Disconnect bool          // Drop the connection without answering.
+
+	Times int // Misbehave on this many requests, then answer normally. 0 is forever.
+	count int
+}
+
+// AddFault adds a fault to the hub. Faults are tried in the order they
+// were added and only the first that matches a request applies.
+func (h *Hub) AddFault(f Fault) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.faults = append(h.faults, &f)
+}
+
+// ClearFaults removes all of the hub's faults.
+func (h *Hub) ClearFaults() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.faults = nil
+}
+
+// matchFault returns the fault to apply to the request, counting it as used.
+// The hub must be locked.
+func (h *Hub) matchFault(method, path string) *Fault {
+	for _, f := range h.faults {
+		if f.Times > 0 && f.count >= f.Times {
+			continue
+		}
+		if f.Method != "" && f.Method != method {
+			continue
+		}
+		if f.Path != "" {
+			if prefix := strings.TrimSuffix(f.Path, "*"); prefix != f.Path {
+				if !strings.HasPrefix(path, prefix) {
+					continue
+				}
+			} else if f.Path != path {
+				continue
+			}
+		}
+		f.count++
+		c := *f
+		return &c
+	}
+	return nil
+}
+
+// inject misbehaves as the fault says. It returns true if the
+// request has been dealt with and shouldn't be answered normally.
+func (f *Fault) inject(w http.ResponseWriter, r *http.Request) bool {
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return true
+		}
+	}
+	if f.Disconnect {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return true
+			}
+		}
+		// Can't drop the connection, so break it another way.
+		panic(http.ErrAbortHandler)
+	}
+	if f.Status == 0 {
+		return false
+	}
+	if f.RetryAfter != "" {
+		w.Header().Set("Retry-After", f.RetryAfter)
+	}
+	message := f.Message
+	if message == "" {
+		message = http.StatusText(f.Status)
+	}
+	writeError(w, f.Status, message)
+	return true
+}
@@ -0,0 +1,127 @@
+package jupyterhubtest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// group is the hub's record of a group of users.
+type group struct {
+	name       string
+	users      map[string]bool
+	properties map[string]interface{}
+}
+
+func newGroup(name string) *group {
+	return &group{name: name, users: make(map[string]bool), properties: map[string]interface{}{}}
+}
+
+func groupModel(g *group) map[string]interface{} {
+	users := []string{}
+	for name := range g.users {
+		users = append(users, name)
+	}
+	sort.Strings(users)
+	return map[string]interface{}{
+		"kind":       "group",
+		"name":       g.name,
+		"users":      users,
+		"roles":      []string{},
+		"properties": g.properties,
+	}
+}
+
+// serveGroups handles /groups and everything under it.
+func (h *Hub) serveGroups(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+		names := make([]string, 0, len(h.groups))
+		for name := range h.groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		items := []interface{}{}
+		for _, name := range names {
+			items = append(items, groupModel(h.groups[name]))
+		}
+		writeList(w, r, items)
+		return
+	}
+
+	name := parts[1]
+	g, ok := h.groups[name]
+	if len(parts) == 2 && r.Method == http.MethodPost {
+		if ok {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Group %s already exists", name))
+			return
+		}
+		g = newGroup(name)
+		h.groups[name] = g
+		writeJSON(w, http.StatusCreated, groupModel(g))
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No such group: %s", name))
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, groupModel(g))
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		delete(h.groups, name)
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 3 && parts[2] == "users":
+		h.serveGroupUsers(w, r, g)
+	case len(parts) == 3 && parts[2] == "properties" && r.Method == http.MethodPut:
+		var properties map[string]interface{}
+		if !readJSON(w, r, &properties) {
+			return
+		}
+		if properties == nil {
+			properties = map[string]interface{}{}
+		}
+		g.properties = properties
+		writeJSON(w, http.StatusOK, groupModel(g))
+	case len(parts) <= 3:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// serveGroupUsers adds users to, or removes them from, a group.
+func (h *Hub) serveGroupUsers(w http.ResponseWriter, r *http.Request, g *group) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	var req struct {
+		Users []string `json:"users"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if len(req.Users) == 0 {
+		writeError(w, http.StatusBadRequest, "Must specify users")
+		return
+	}
+	for _, name := range req.Users {
+		if _, ok := h.users[name]; !ok {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("No such user: %s", name))
+			return
+		}
+	}
+	for _, name := range req.Users {
+		if r.Method == http.MethodPost {
+			g.users[name] = true
+		} else {
+			delete(g.users, name)
+		}
+	}
+	writeJSON(w, http.StatusOK, groupModel(g))
+}
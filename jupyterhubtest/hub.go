@@ -0,0 +1,393 @@
+// Package jupyterhubtest provides an in-memory JupyterHub, for testing
+// code that uses the hub's REST API without running a real hub.
+//
+// The hub keeps users, groups, servers, tokens, services and shares in memory and
+// answers the REST API under /hub/api with the same status codes as
+// JupyterHub: 201 and 202 for creating and starting things, 204 for
+// deleting and stopping them, 404 for things that don't exist and 403
+// for tokens that aren't allowed. Spawns can be slowed down with SpawnDelay,
+// and any request can be made to fail with AddFault.
+//
+// Typical use:
+//
+//	hub := jupyterhubtest.NewHub()
+//	hub.AddUser("alice", false)
+//	srv := jupyterhubtest.NewServer(hub)
+//	defer srv.Close()
+//	conn := jupyterhub.Connection{HubURL: srv.APIURL, Token: jupyterhubtest.AdminToken}
+package jupyterhubtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIPrefix is where the hub serves its REST API.
+const APIPrefix = "/hub/api"
+
+// AdminToken is the API token of the hub's "admin" user, who is an admin.
+const AdminToken = "jupyterhubtest-admin-token"
+
+// DefaultVersion is the JupyterHub version the hub reports.
+const DefaultVersion = "5.2.1"
+
+// Hub is an in-memory JupyterHub. It is an http.Handler.
+// The exported fields change how the hub behaves, and should be set
+// before it starts handling requests.
+type Hub struct {
+	Version          string        // Reported by / and /info, DefaultVersion by default. Before 2, user lists ignore state, before 5 there's no sharing.
+	SpawnDelay       time.Duration // How long servers take to start, starts are 202 Accepted if more than 0.
+	StopDelay        time.Duration // How long servers take to stop, stops are 202 Accepted if more than 0.
+	SpawnFailure     string        // If set, every spawn fails with this message.
+	NamedServerLimit int           // Named servers allowed for each user, 0 is no limit.
+
+	mu            sync.Mutex
+	users         map[string]*user
+	groups        map[string]*group
+	services      map[string]*service
+	tokens        map[string]tokenOwner // Keyed by the token itself.
+	faults        []*Fault
+	shares        []*share
+	shareCodes    []*shareCode
+	requests      []string
+	proxyAPI      string
+	shutdown      bool
+	nextToken     int
+	nextShareCode int
+}
+
+// tokenOwner is who a token belongs to, one of user or service.
+type tokenOwner struct {
+	user    string
+	service string
+	id      string // The id of a user's token, "" for tokens added with AddUserToken.
+}
+
+// NewHub returns a hub with a single admin user, "admin", whose token is AdminToken.
+func NewHub() *Hub {
+	h := &Hub{
+		Version:  DefaultVersion,
+		users:    make(map[string]*user),
+		groups:   make(map[string]*group),
+		services: make(map[string]*service),
+		tokens:   make(map[string]tokenOwner),
+	}
+	h.AddUser("admin", true)
+	h.AddUserToken("admin", AdminToken)
+	return h
+}
+
+// AddUser adds the user name to the hub, or changes their admin status
+// if they're already there.
+func (h *Hub) AddUser(name string, admin bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if u, ok := h.users[name]; ok {
+		u.admin = admin
+		return
+	}
+	h.users[name] = newUser(name, admin)
+}
+
+// AddUserToken gives the user name, who is added if need be, the API token token.
+func (h *Hub) AddUserToken(name, token string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.users[name]; !ok {
+		h.users[name] = newUser(name, false)
+	}
+	h.tokens[token] = tokenOwner{user: name}
+}
+
+// AddGroup adds the group name, with usernames as its members.
+// Users that aren't on the hub are added.
+func (h *Hub) AddGroup(name string, usernames ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	g, ok := h.groups[name]
+	if !ok {
+		g = newGroup(name)
+		h.groups[name] = g
+	}
+	for _, un := range usernames {
+		if _, ok := h.users[un]; !ok {
+			h.users[un] = newUser(un, false)
+		}
+		g.users[un] = true
+	}
+}
+
+// AddService adds a service as if it were in the hub's configuration (so it
+// can't be deleted through the API), running at url and with the API token token.
+func (h *Hub) AddService(name, url, token string, admin bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.services[name] = &service{name: name, url: url, admin: admin, info: map[string]interface{}{}, display: true}
+	if token != "" {
+		h.tokens[token] = tokenOwner{service: name}
+	}
+}
+
+// StartServer starts username's server servername ("" for the default server)
+// straight away, ready to use. The user is added if need be.
+func (h *Hub) StartServer(username, servername string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	u, ok := h.users[username]
+	if !ok {
+		u = newUser(username, false)
+		h.users[username] = u
+	}
+	if servername != "" {
+		u.named[servername] = true
+	}
+	now := time.Now()
+	u.servers[servername] = &server{name: servername, ready: true, started: now, lastActivity: now}
+}
+
+// Requests returns the requests the hub has received, in order,
+// as "METHOD /path" with the path relative to APIPrefix.
+func (h *Hub) Requests() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.requests...)
+}
+
+// ShutdownRequested is true once the hub has been asked to shut down.
+func (h *Hub) ShutdownRequested() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.shutdown
+}
+
+// ProxyAPIURL is the proxy API URL the hub was last given, if any.
+func (h *Hub) ProxyAPIURL() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.proxyAPI
+}
+
+// ServeHTTP answers the hub's REST API.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if !strings.HasPrefix(path, APIPrefix+"/") && path != APIPrefix {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	path = strings.TrimPrefix(path, APIPrefix)
+	if path == "" {
+		path = "/"
+	}
+
+	h.mu.Lock()
+	h.requests = append(h.requests, fmt.Sprintf("%s %s", r.Method, path))
+	fault := h.matchFault(r.Method, path)
+	h.mu.Unlock()
+
+	if fault != nil && fault.inject(w, r) {
+		return
+	}
+
+	w.Header().Set("X-JupyterHub-Version", h.Version)
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if path == "/" {
+		parts = nil
+	}
+
+	// The version is the one thing that doesn't need a token.
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"version": h.Version})
+		return
+	}
+
+	// Progress streams for as long as the spawn takes, so it manages the lock itself.
+	if isProgress(parts) {
+		h.mu.Lock()
+		_, ok := h.authorize(w, r, parts)
+		h.mu.Unlock()
+		if ok {
+			h.serveProgress(w, r, parts[1], serverName(parts))
+		}
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	caller, ok := h.authorize(w, r, parts)
+	if !ok {
+		return
+	}
+
+	switch parts[0] {
+	case "users":
+		h.serveUsers(w, r, parts)
+	case "user":
+		h.serveWhoami(w, r, caller)
+	case "groups":
+		h.serveGroups(w, r, parts)
+	case "services":
+		h.serveServices(w, r, parts)
+	case "proxy":
+		h.serveProxy(w, r)
+	case "info":
+		h.serveInfo(w, r)
+	case "shutdown":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+		h.shutdown = true
+		w.WriteHeader(http.StatusAccepted)
+	case "authorizations":
+		h.serveAuthorizations(w, r, parts)
+	case "shares", "share-codes":
+		// Sharing came with JupyterHub 5.
+		if h.older("5") {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		if parts[0] == "shares" {
+			h.serveShares(w, r, parts)
+		} else {
+			h.serveShareCodes(w, r, parts)
+		}
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// authorize checks the request's token, and that its owner may make the request.
+// Admins may do anything. Other users may only look at themselves, and manage
+// their own servers, tokens and activity. If the request isn't allowed,
+// the error is written and ok is false. The hub must be locked.
+func (h *Hub) authorize(w http.ResponseWriter, r *http.Request, parts []string) (caller tokenOwner, ok bool) {
+	token := ""
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		fields := strings.Fields(auth)
+		if len(fields) == 2 && (strings.EqualFold(fields[0], "token") || strings.EqualFold(fields[0], "bearer")) {
+			token = fields[1]
+		}
+	}
+	caller, ok = h.tokens[token]
+	if !ok || h.expired(token, caller) {
+		writeError(w, http.StatusForbidden, "Forbidden")
+		return caller, false
+	}
+	if h.isAdmin(caller) {
+		return caller, true
+	}
+
+	switch {
+	case parts[0] == "user" || parts[0] == "authorizations":
+		return caller, true
+	case parts[0] == "users" && len(parts) >= 2 && caller.user != "" && parts[1] == caller.user:
+		// Users can't make themselves admins.
+		if len(parts) == 2 && r.Method != http.MethodGet {
+			break
+		}
+		return caller, true
+	}
+	writeError(w, http.StatusForbidden, "Action is not authorized with current scopes; requires any of [admin:users]")
+	return caller, false
+}
+
+func (h *Hub) isAdmin(caller tokenOwner) bool {
+	if caller.user != "" {
+		u, ok := h.users[caller.user]
+		return ok && u.admin
+	}
+	s, ok := h.services[caller.service]
+	return ok && s.admin
+}
+
+// expired is true if the token is one of a user's tokens that has expired.
+func (h *Hub) expired(token string, caller tokenOwner) bool {
+	if caller.id == "" {
+		return false
+	}
+	u, ok := h.users[caller.user]
+	if !ok {
+		return true
+	}
+	for _, t := range u.tokens {
+		if t.id == caller.id {
+			return !t.expires.IsZero() && time.Now().After(t.expires)
+		}
+	}
+	return true
+}
+
+// newToken returns a new random token.
+func (h *Hub) newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		h.nextToken++
+		return fmt.Sprintf("jupyterhubtest-token-%d", h.nextToken)
+	}
+	return hex.EncodeToString(b)
+}
+
+//
+// Helpers for writing responses.
+//
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the JSON error body the hub sends.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{"status": status, "message": message})
+}
+
+// readJSON decodes the request body into v, writing a 400 if it can't.
+// An empty body leaves v as it is.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Body == nil {
+		return true
+	}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON in body of request: %v", err))
+		return false
+	}
+	return true
+}
+
+// isoTime formats t the way the hub does, or nil for the zero time.
+func isoTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.000000Z")
+}
+
+// nullString is nil for "", so it's sent as null.
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// older is true if the hub's major version is before major, e.g. "2".
+func (h *Hub) older(major string) bool {
+	v, err := strconv.Atoi(strings.SplitN(h.Version, ".", 2)[0])
+	m, _ := strconv.Atoi(major)
+	return err == nil && v < m
+}
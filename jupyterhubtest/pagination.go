@@ -0,0 +1,77 @@
+package jupyterhubtest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// paginationContentType is the Accept type that asks for paginated lists.
+const paginationContentType = "application/jupyterhub-pagination+json"
+
+// maxPageSize is the most items the hub sends at once.
+const maxPageSize = 200
+
+// writeList writes the page of items asked for by the request's offset and limit.
+// As with JupyterHub 2+, the page is wrapped with its pagination details only when
+// the request accepts the pagination content type, otherwise it's a plain list.
+func writeList(w http.ResponseWriter, r *http.Request, items []interface{}) {
+	q := r.URL.Query()
+	offset, limit := 0, maxPageSize
+	var err error
+	if s := q.Get("offset"); s != "" {
+		if offset, err = strconv.Atoi(s); err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid offset: %q", s))
+			return
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit: %q", s))
+			return
+		}
+		if limit == 0 || limit > maxPageSize {
+			limit = maxPageSize
+		}
+	}
+
+	total := len(items)
+	start, end := offset, offset+limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+	page := items[start:end]
+	if page == nil {
+		page = []interface{}{}
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), paginationContentType) {
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+
+	var next interface{}
+	if end < total {
+		nq := r.URL.Query()
+		nq.Set("offset", strconv.Itoa(end))
+		nq.Set("limit", strconv.Itoa(limit))
+		next = map[string]interface{}{
+			"offset": end,
+			"limit":  limit,
+			"url":    fmt.Sprintf("%s?%s", r.URL.Path, nq.Encode()),
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"items": page,
+		"_pagination": map[string]interface{}{
+			"offset": offset,
+			"limit":  limit,
+			"total":  total,
+			"next":   next,
+		},
+	})
+}
@@ -0,0 +1,18 @@
+package jupyterhubtest
+
+import (
+	"net/http/httptest"
+)
+
+// Server is a hub listening on a local port.
+type Server struct {
+	*httptest.Server
+	Hub    *Hub
+	APIURL string // The URL of the hub's REST API, for a connection's HubURL.
+}
+
+// NewServer starts a server for hub, which should be closed when done with.
+func NewServer(hub *Hub) *Server {
+	s := httptest.NewServer(hub)
+	return &Server{Server: s, Hub: hub, APIURL: s.URL + APIPrefix}
+}
@@ -0,0 +1,225 @@
+package jupyterhubtest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// service is the hub's record of a service.
+type service struct {
+	name          string
+	url           string
+	admin         bool
+	info          map[string]interface{}
+	oauthClientID string
+	display       bool
+	fromAPI       bool // Added through the API rather than the hub's configuration, so it can be deleted.
+}
+
+func serviceModel(s *service) map[string]interface{} {
+	prefix := ""
+	if s.url != "" {
+		prefix = fmt.Sprintf("/services/%s/", s.name)
+	}
+	roles := []string{}
+	if s.admin {
+		roles = []string{"admin"}
+	}
+	return map[string]interface{}{
+		"kind":            "service",
+		"name":            s.name,
+		"admin":           s.admin,
+		"roles":           roles,
+		"url":             s.url,
+		"prefix":          prefix,
+		"pid":             0,
+		"command":         []string{},
+		"info":            s.info,
+		"display":         s.display,
+		"oauth_client_id": nullString(s.oauthClientID),
+	}
+}
+
+// serveServices handles /services and everything under it.
+func (h *Hub) serveServices(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+		// Services are an object keyed by name, not a list.
+		services := make(map[string]interface{})
+		for name, s := range h.services {
+			services[name] = serviceModel(s)
+		}
+		writeJSON(w, http.StatusOK, services)
+		return
+	}
+	if len(parts) > 2 {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	name := parts[1]
+	s, ok := h.services[name]
+	switch r.Method {
+	case http.MethodGet:
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("No such service: %s", name))
+			return
+		}
+		writeJSON(w, http.StatusOK, serviceModel(s))
+
+	case http.MethodPost:
+		if ok {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Service %s already exists", name))
+			return
+		}
+		var req struct {
+			URL           string                 `json:"url"`
+			Admin         bool                   `json:"admin"`
+			OAuthClientID string                 `json:"oauth_client_id"`
+			APIToken      string                 `json:"api_token"`
+			Display       *bool                  `json:"display"`
+			Info          map[string]interface{} `json:"info"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		s = &service{name: name, url: req.URL, admin: req.Admin, info: req.Info,
+			oauthClientID: req.OAuthClientID, display: true, fromAPI: true}
+		if s.info == nil {
+			s.info = map[string]interface{}{}
+		}
+		if req.Display != nil {
+			s.display = *req.Display
+		}
+		token := req.APIToken
+		if token == "" {
+			token = h.newToken()
+		}
+		h.services[name] = s
+		h.tokens[token] = tokenOwner{service: name}
+		m := serviceModel(s)
+		m["api_token"] = token
+		writeJSON(w, http.StatusCreated, m)
+
+	case http.MethodDelete:
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("No such service: %s", name))
+			return
+		}
+		if !s.fromAPI {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Service %s is not modifiable at runtime", name))
+			return
+		}
+		delete(h.services, name)
+		for token, owner := range h.tokens {
+			if owner.service == name {
+				delete(h.tokens, token)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+// serveProxy reports the routes the proxy would have, syncs it, and changes
+// where the hub finds it.
+func (h *Hub) serveProxy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		routes := map[string]interface{}{
+			"/": map[string]interface{}{
+				"routespec": "/",
+				"target":    "http://127.0.0.1:8081",
+				"data":      map[string]interface{}{"hub": true},
+			},
+		}
+		names := make([]string, 0, len(h.users))
+		for name := range h.users {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		port := 9000
+		for _, name := range names {
+			u := h.users[name]
+			h.refresh(u)
+			for servername, s := range u.servers {
+				if !s.ready {
+					continue
+				}
+				port++
+				spec := serverPath(u, servername)
+				routes[spec] = map[string]interface{}{
+					"routespec": spec,
+					"target":    fmt.Sprintf("http://127.0.0.1:%d", port),
+					"data": map[string]interface{}{
+						"user":          u.name,
+						"server_name":   servername,
+						"last_activity": isoTime(s.lastActivity),
+					},
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, routes)
+
+	case http.MethodPost:
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodPatch:
+		var req struct {
+			APIURL    string `json:"api_url"`
+			AuthToken string `json:"auth_token"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if req.APIURL != "" {
+			h.proxyAPI = req.APIURL
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (h *Hub) serveInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"version":        h.Version,
+		"python":         "3.12.3 (main) [jupyterhubtest]",
+		"sys_executable": "/usr/bin/python3",
+		"authenticator":  map[string]string{"class": "jupyterhub.auth.DummyAuthenticator", "version": h.Version},
+		"spawner":        map[string]string{"class": "jupyterhubtest.Spawner", "version": h.Version},
+	})
+}
+
+// serveAuthorizations answers /authorizations/token/:token with the token's owner.
+func (h *Hub) serveAuthorizations(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) != 3 || parts[1] != "token" {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	owner, ok := h.tokens[parts[2]]
+	if !ok || h.expired(parts[2], owner) {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if owner.user != "" {
+		writeJSON(w, http.StatusOK, h.userModel(h.users[owner.user]))
+		return
+	}
+	writeJSON(w, http.StatusOK, serviceModel(h.services[owner.service]))
+}
@@ -0,0 +1,317 @@
+package jupyterhubtest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultShareCodeExpiry is how long share codes last when the request doesn't say.
+const defaultShareCodeExpiry = 24 * time.Hour
+
+// share is access to one of a user's servers, granted to a user or a group.
+type share struct {
+	owner, server string
+	user, group   string // Who it's shared with, one of them is set.
+	scopes        []string
+	created       time.Time
+}
+
+// shareCode grants a share of a server to whoever accepts it.
+type shareCode struct {
+	id, code      string
+	owner, server string
+	scopes        []string
+	created       time.Time
+	expires       time.Time
+}
+
+// accessScope is the scope a share grants when the request doesn't say.
+func accessScope(owner, servername string) string {
+	return fmt.Sprintf("access:servers!server=%s/%s", owner, servername)
+}
+
+func (h *Hub) sharedServerModel(owner, servername string) map[string]interface{} {
+	ready := false
+	url := fmt.Sprintf("/user/%s/%s", owner, servername)
+	if u, ok := h.users[owner]; ok {
+		h.refresh(u)
+		s, running := u.servers[servername]
+		ready = running && s.ready
+		url = serverPath(u, servername)
+	}
+	return map[string]interface{}{
+		"name":  servername,
+		"user":  map[string]string{"name": owner},
+		"url":   url,
+		"ready": ready,
+	}
+}
+
+func (h *Hub) shareModel(s *share) map[string]interface{} {
+	model := map[string]interface{}{
+		"kind":       "user",
+		"server":     h.sharedServerModel(s.owner, s.server),
+		"scopes":     s.scopes,
+		"user":       nil,
+		"group":      nil,
+		"created_at": isoTime(s.created),
+	}
+	if s.group != "" {
+		model["kind"] = "group"
+		model["group"] = map[string]string{"name": s.group}
+	} else {
+		model["user"] = map[string]string{"name": s.user}
+	}
+	return model
+}
+
+func (h *Hub) shareCodeModel(c *shareCode) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                c.id,
+		"server":            h.sharedServerModel(c.owner, c.server),
+		"scopes":            c.scopes,
+		"created_at":        isoTime(c.created),
+		"expires_at":        isoTime(c.expires),
+		"exchange_count":    0,
+		"last_exchanged_at": nil,
+	}
+}
+
+// sharedServer finds the owner and server of a /shares/... or /share-codes/... request,
+// writing a 404 if there isn't one. The default server's path ends in a slash,
+// e.g. /shares/alice/, without one it's all of the owner's servers (servername is "").
+func (h *Hub) sharedServer(w http.ResponseWriter, r *http.Request, parts []string) (owner *user, servername string, all, ok bool) {
+	if len(parts) < 2 || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil, "", false, false
+	}
+	owner, ok = h.users[parts[1]]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No such user: %s", parts[1]))
+		return nil, "", false, false
+	}
+	if len(parts) == 2 {
+		return owner, "", !strings.HasSuffix(r.URL.Path, "/"), true
+	}
+	servername = parts[2]
+	if !owner.named[servername] {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No such server: %s", owner.logName(servername)))
+		return nil, "", false, false
+	}
+	return owner, servername, false, true
+}
+
+// serveShares handles /shares/<owner> and /shares/<owner>/<server-name>.
+func (h *Hub) serveShares(w http.ResponseWriter, r *http.Request, parts []string) {
+	owner, servername, all, ok := h.sharedServer(w, r, parts)
+	if !ok {
+		return
+	}
+	if all && r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		items := []interface{}{}
+		for _, s := range h.shares {
+			if s.owner == owner.name && (all || s.server == servername) {
+				items = append(items, h.shareModel(s))
+			}
+		}
+		writeList(w, r, items)
+
+	case http.MethodPost, http.MethodPatch:
+		var req struct {
+			User   string   `json:"user"`
+			Group  string   `json:"group"`
+			Scopes []string `json:"scopes"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if (req.User == "") == (req.Group == "") {
+			writeError(w, http.StatusBadRequest, "Specify exactly one of 'user' or 'group'")
+			return
+		}
+		if _, ok := h.users[req.User]; req.User != "" && !ok {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("No such user: %s", req.User))
+			return
+		}
+		if _, ok := h.groups[req.Group]; req.Group != "" && !ok {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("No such group: %s", req.Group))
+			return
+		}
+		if r.Method == http.MethodPost {
+			h.grantShare(w, owner.name, servername, req.User, req.Group, req.Scopes)
+		} else {
+			h.revokeShare(w, owner.name, servername, req.User, req.Group, req.Scopes)
+		}
+
+	case http.MethodDelete:
+		kept := h.shares[:0]
+		for _, s := range h.shares {
+			if s.owner != owner.name || s.server != servername {
+				kept = append(kept, s)
+			}
+		}
+		h.shares = kept
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+// findShare returns the share of owner's server with user or group, if there is one.
+func (h *Hub) findShare(owner, servername, username, groupname string) (i int, ok bool) {
+	for i, s := range h.shares {
+		if s.owner == owner && s.server == servername && s.user == username && s.group == groupname {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// grantShare adds scopes to the share with user or group, making it if need be.
+func (h *Hub) grantShare(w http.ResponseWriter, owner, servername, username, groupname string, scopes []string) {
+	if len(scopes) == 0 {
+		scopes = []string{accessScope(owner, servername)}
+	}
+	i, ok := h.findShare(owner, servername, username, groupname)
+	if !ok {
+		h.shares = append(h.shares, &share{owner: owner, server: servername, user: username, group: groupname, created: time.Now()})
+		i = len(h.shares) - 1
+	}
+	s := h.shares[i]
+	for _, scope := range scopes {
+		if !contains(s.scopes, scope) {
+			s.scopes = append(s.scopes, scope)
+		}
+	}
+	writeJSON(w, http.StatusOK, h.shareModel(s))
+}
+
+// revokeShare takes scopes (all of them if there are none) away from the share with
+// user or group. A share left without scopes is removed, and answered with {}.
+func (h *Hub) revokeShare(w http.ResponseWriter, owner, servername, username, groupname string, scopes []string) {
+	i, ok := h.findShare(owner, servername, username, groupname)
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+		return
+	}
+	s := h.shares[i]
+	kept := []string{}
+	for _, scope := range s.scopes {
+		if len(scopes) > 0 && !contains(scopes, scope) {
+			kept = append(kept, scope)
+		}
+	}
+	if len(kept) == 0 {
+		h.shares = append(h.shares[:i], h.shares[i+1:]...)
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+		return
+	}
+	s.scopes = kept
+	writeJSON(w, http.StatusOK, h.shareModel(s))
+}
+
+// serveSharedWith lists the shares granted to the user u.
+func (h *Hub) serveSharedWith(w http.ResponseWriter, r *http.Request, u *user) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	items := []interface{}{}
+	for _, s := range h.shares {
+		if s.user == u.name {
+			items = append(items, h.shareModel(s))
+		}
+	}
+	writeList(w, r, items)
+}
+
+// serveShareCodes handles /share-codes/<owner>/<server-name>.
+func (h *Hub) serveShareCodes(w http.ResponseWriter, r *http.Request, parts []string) {
+	owner, servername, all, ok := h.sharedServer(w, r, parts)
+	if !ok {
+		return
+	}
+	if all {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		items := []interface{}{}
+		for _, c := range h.shareCodes {
+			if c.owner == owner.name && c.server == servername {
+				items = append(items, h.shareCodeModel(c))
+			}
+		}
+		writeList(w, r, items)
+
+	case http.MethodPost:
+		var req struct {
+			ExpiresIn int      `json:"expires_in"`
+			Scopes    []string `json:"scopes"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		expiry := defaultShareCodeExpiry
+		if req.ExpiresIn > 0 {
+			expiry = time.Duration(req.ExpiresIn) * time.Second
+		}
+		scopes := req.Scopes
+		if len(scopes) == 0 {
+			scopes = []string{accessScope(owner.name, servername)}
+		}
+		h.nextShareCode++
+		now := time.Now()
+		c := &shareCode{
+			id:      fmt.Sprintf("sc_%d", h.nextShareCode),
+			code:    h.newToken(),
+			owner:   owner.name,
+			server:  servername,
+			scopes:  scopes,
+			created: now,
+			expires: now.Add(expiry),
+		}
+		h.shareCodes = append(h.shareCodes, c)
+		model := h.shareCodeModel(c)
+		model["code"] = c.code
+		model["accept_url"] = fmt.Sprintf("/hub/accept-share?code=%s", c.code)
+		model["full_accept_url"] = fmt.Sprintf("http://%s/hub/accept-share?code=%s", r.Host, c.code)
+		writeJSON(w, http.StatusOK, model)
+
+	case http.MethodDelete:
+		id, code := r.URL.Query().Get("id"), r.URL.Query().Get("code")
+		kept := h.shareCodes[:0]
+		for _, c := range h.shareCodes {
+			revoked := c.owner == owner.name && c.server == servername &&
+				(id == "" || c.id == id) && (code == "" || c.code == code)
+			if !revoked {
+				kept = append(kept, c)
+			}
+		}
+		h.shareCodes = kept
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,711 @@
+package jupyterhubtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// user is the hub's record of a user.
+type user struct {
+	name         string
+	admin        bool
+	created      time.Time
+	lastActivity time.Time
+	servers      map[string]*server // Running, or starting or stopping, keyed by name. "" is the default server.
+	named        map[string]bool    // The named servers the user has, running or not.
+	failures     map[string]string  // Why the last spawn of a server failed.
+	tokens       []*apiToken
+	nextTokenID  int
+}
+
+func newUser(name string, admin bool) *user {
+	return &user{
+		name:     name,
+		admin:    admin,
+		created:  time.Now(),
+		servers:  make(map[string]*server),
+		named:    make(map[string]bool),
+		failures: make(map[string]string),
+	}
+}
+
+// logName is how the hub refers to one of the user's servers in messages.
+func (u *user) logName(servername string) string {
+	if servername == "" {
+		return u.name
+	}
+	return fmt.Sprintf("%s:%s", u.name, servername)
+}
+
+// server is a single server of a user.
+type server struct {
+	name         string
+	ready        bool
+	pending      string // "spawn", "stop" or "".
+	started      time.Time
+	lastActivity time.Time
+	readyAt      time.Time // When a pending spawn finishes.
+	stopAt       time.Time // When a pending stop finishes.
+	failure      string    // Why a pending spawn will fail, if it will.
+	options      map[string]interface{}
+}
+
+// apiToken is one of a user's API tokens.
+type apiToken struct {
+	id           string
+	token        string
+	note         string
+	scopes       []string
+	roles        []string
+	created      time.Time
+	expires      time.Time
+	lastActivity time.Time
+}
+
+// refresh brings the user's servers up to date with any pending spawns and stops
+// that have finished. The hub must be locked.
+func (h *Hub) refresh(u *user) {
+	now := time.Now()
+	for name, s := range u.servers {
+		switch {
+		case s.pending == "spawn" && !now.Before(s.readyAt):
+			if s.failure != "" {
+				u.failures[name] = s.failure
+				delete(u.servers, name)
+				continue
+			}
+			s.pending, s.ready = "", true
+		case s.pending == "stop" && !now.Before(s.stopAt):
+			delete(u.servers, name)
+		}
+	}
+}
+
+func (h *Hub) userModel(u *user) map[string]interface{} {
+	h.refresh(u)
+	servers := make(map[string]interface{})
+	for name, s := range u.servers {
+		servers[name] = serverModel(u, s)
+	}
+	var serverURL, pending interface{}
+	if s, ok := u.servers[""]; ok {
+		if s.ready {
+			serverURL = serverPath(u, "")
+		}
+		pending = nullString(s.pending)
+	}
+	roles := []string{"user"}
+	if u.admin {
+		roles = []string{"admin", "user"}
+	}
+	return map[string]interface{}{
+		"kind":          "user",
+		"name":          u.name,
+		"admin":         u.admin,
+		"roles":         roles,
+		"groups":        h.userGroups(u.name),
+		"server":        serverURL,
+		"pending":       pending,
+		"created":       isoTime(u.created),
+		"last_activity": isoTime(u.lastActivity),
+		"servers":       servers,
+	}
+}
+
+func serverModel(u *user, s *server) map[string]interface{} {
+	progress := fmt.Sprintf("%s/users/%s/server/progress", APIPrefix, u.name)
+	if s.name != "" {
+		progress = fmt.Sprintf("%s/users/%s/servers/%s/progress", APIPrefix, u.name, s.name)
+	}
+	options := s.options
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"name":          s.name,
+		"ready":         s.ready,
+		"pending":       nullString(s.pending),
+		"url":           serverPath(u, s.name),
+		"progress_url":  progress,
+		"started":       isoTime(s.started),
+		"last_activity": isoTime(s.lastActivity),
+		"state":         map[string]interface{}{},
+		"user_options":  options,
+	}
+}
+
+// serverPath is where the proxy sends a user's server.
+func serverPath(u *user, servername string) string {
+	if servername == "" {
+		return fmt.Sprintf("/user/%s/", u.name)
+	}
+	return fmt.Sprintf("/user/%s/%s/", u.name, servername)
+}
+
+func tokenModel(u *user, t *apiToken) map[string]interface{} {
+	scopes := t.scopes
+	if scopes == nil {
+		scopes = []string{"inherit"}
+	}
+	m := map[string]interface{}{
+		"kind":          "api_token",
+		"id":            t.id,
+		"user":          u.name,
+		"service":       nil,
+		"note":          t.note,
+		"scopes":        scopes,
+		"created":       isoTime(t.created),
+		"expires_at":    isoTime(t.expires),
+		"last_activity": isoTime(t.lastActivity),
+	}
+	if t.roles != nil {
+		m["roles"] = t.roles
+	}
+	return m
+}
+
+// userGroups returns the names of the groups username is in, sorted.
+func (h *Hub) userGroups(username string) []string {
+	groups := []string{}
+	for name, g := range h.groups {
+		if g.users[username] {
+			groups = append(groups, name)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// serveUsers handles /users and everything under it, except for progress.
+func (h *Hub) serveUsers(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			h.listUsers(w, r)
+		case http.MethodPost:
+			h.createUsers(w, r)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		}
+		return
+	}
+
+	name := parts[1]
+	u, ok := h.users[name]
+	if len(parts) == 2 && r.Method == http.MethodPost {
+		h.createUser(w, r, name, ok)
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No such user: %s", name))
+		return
+	}
+
+	switch {
+	case len(parts) == 2:
+		h.serveUser(w, r, u)
+	case len(parts) == 3 && parts[2] == "server":
+		h.serveServer(w, r, u, "")
+	case len(parts) == 4 && parts[2] == "servers":
+		h.serveServer(w, r, u, parts[3])
+	case len(parts) == 3 && parts[2] == "tokens":
+		h.serveTokens(w, r, u)
+	case len(parts) == 4 && parts[2] == "tokens":
+		h.serveToken(w, r, u, parts[3])
+	case len(parts) == 3 && parts[2] == "activity":
+		h.serveActivity(w, r, u)
+	case len(parts) == 3 && parts[2] == "shared" && !h.older("5"):
+		h.serveSharedWith(w, r, u)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// listUsers lists the users sorted by name, filtered by the state parameter.
+func (h *Hub) listUsers(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+	if h.older("2") {
+		// The state filter came with JupyterHub 2.0, before that it's ignored.
+		state = ""
+	}
+	switch state {
+	case "", "active", "inactive", "ready":
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Unrecognized state: %s", state))
+		return
+	}
+
+	names := make([]string, 0, len(h.users))
+	for name := range h.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := []interface{}{}
+	for _, name := range names {
+		u := h.users[name]
+		h.refresh(u)
+		ready := false
+		for _, s := range u.servers {
+			ready = ready || s.ready
+		}
+		active := len(u.servers) > 0
+		if (state == "active" && !active) || (state == "inactive" && active) || (state == "ready" && !ready) {
+			continue
+		}
+		items = append(items, h.userModel(u))
+	}
+	writeList(w, r, items)
+}
+
+// createUsers creates each of the users in the request that don't exist yet.
+func (h *Hub) createUsers(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Usernames []string `json:"usernames"`
+		Admin     bool     `json:"admin"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if len(req.Usernames) == 0 {
+		writeError(w, http.StatusBadRequest, "Must specify at least one user to create")
+		return
+	}
+	created := []interface{}{}
+	for _, name := range req.Usernames {
+		if _, ok := h.users[name]; ok {
+			continue
+		}
+		u := newUser(name, req.Admin)
+		h.users[name] = u
+		created = append(created, h.userModel(u))
+	}
+	if len(created) == 0 {
+		writeError(w, http.StatusConflict, fmt.Sprintf("All %d users already exist", len(req.Usernames)))
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *Hub) createUser(w http.ResponseWriter, r *http.Request, name string, exists bool) {
+	if exists {
+		writeError(w, http.StatusConflict, fmt.Sprintf("User %s already exists", name))
+		return
+	}
+	var req struct {
+		Admin bool `json:"admin"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	u := newUser(name, req.Admin)
+	h.users[name] = u
+	writeJSON(w, http.StatusCreated, h.userModel(u))
+}
+
+func (h *Hub) serveUser(w http.ResponseWriter, r *http.Request, u *user) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, h.userModel(u))
+
+	case http.MethodPatch:
+		var req struct {
+			Name  *string `json:"name"`
+			Admin *bool   `json:"admin"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if req.Name != nil && *req.Name != u.name {
+			if _, ok := h.users[*req.Name]; ok {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("User %s already exists, username must be unique", *req.Name))
+				return
+			}
+			h.renameUser(u, *req.Name)
+		}
+		if req.Admin != nil {
+			u.admin = *req.Admin
+		}
+		writeJSON(w, http.StatusOK, h.userModel(u))
+
+	case http.MethodDelete:
+		delete(h.users, u.name)
+		for _, g := range h.groups {
+			delete(g.users, u.name)
+		}
+		for token, owner := range h.tokens {
+			if owner.user == u.name {
+				delete(h.tokens, token)
+			}
+		}
+		kept := h.shares[:0]
+		for _, s := range h.shares {
+			if s.owner != u.name && s.user != u.name {
+				kept = append(kept, s)
+			}
+		}
+		h.shares = kept
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (h *Hub) renameUser(u *user, name string) {
+	old := u.name
+	delete(h.users, old)
+	u.name = name
+	h.users[name] = u
+	for _, g := range h.groups {
+		if g.users[old] {
+			delete(g.users, old)
+			g.users[name] = true
+		}
+	}
+	for token, owner := range h.tokens {
+		if owner.user == old {
+			owner.user = name
+			h.tokens[token] = owner
+		}
+	}
+}
+
+// serveServer starts and stops one of the user's servers.
+func (h *Hub) serveServer(w http.ResponseWriter, r *http.Request, u *user, servername string) {
+	h.refresh(u)
+	switch r.Method {
+	case http.MethodPost:
+		h.startServer(w, r, u, servername)
+	case http.MethodDelete:
+		h.stopServer(w, r, u, servername)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (h *Hub) startServer(w http.ResponseWriter, r *http.Request, u *user, servername string) {
+	logName := u.logName(servername)
+	if servername != "" && !u.named[servername] && h.NamedServerLimit > 0 && len(u.named) >= h.NamedServerLimit {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("User %s already has the maximum of %d named servers.  One must be deleted before a new server can be created",
+			u.name, h.NamedServerLimit))
+		return
+	}
+	if s, ok := u.servers[servername]; ok {
+		if s.pending != "" {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("%s is pending %s", logName, s.pending))
+		} else {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("%s is already running", logName))
+		}
+		return
+	}
+	var options map[string]interface{}
+	if !readJSON(w, r, &options) {
+		return
+	}
+
+	delete(u.failures, servername)
+	if h.SpawnDelay <= 0 && h.SpawnFailure != "" {
+		u.failures[servername] = h.SpawnFailure
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Spawn failed: %s", h.SpawnFailure))
+		return
+	}
+
+	if servername != "" {
+		u.named[servername] = true
+	}
+	now := time.Now()
+	s := &server{name: servername, started: now, lastActivity: now, options: options}
+	u.servers[servername] = s
+	if h.SpawnDelay <= 0 {
+		s.ready = true
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	s.pending, s.readyAt, s.failure = "spawn", now.Add(h.SpawnDelay), h.SpawnFailure
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Hub) stopServer(w http.ResponseWriter, r *http.Request, u *user, servername string) {
+	var req struct {
+		Remove bool `json:"remove"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if servername != "" && !u.named[servername] {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s has no server named %s", u.name, servername))
+		return
+	}
+	if servername == "" && req.Remove {
+		writeError(w, http.StatusBadRequest, "Cannot delete the default server")
+		return
+	}
+	if req.Remove {
+		delete(u.named, servername)
+	}
+
+	s, ok := u.servers[servername]
+	switch {
+	case !ok:
+		w.WriteHeader(http.StatusNoContent)
+	case s.pending == "stop":
+		w.WriteHeader(http.StatusAccepted)
+	case s.pending != "":
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("%s is pending %s, please wait", u.logName(servername), s.pending))
+	case h.StopDelay <= 0:
+		delete(u.servers, servername)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.ready, s.pending, s.stopAt = false, "stop", time.Now().Add(h.StopDelay)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (h *Hub) serveTokens(w http.ResponseWriter, r *http.Request, u *user) {
+	switch r.Method {
+	case http.MethodGet:
+		tokens := []interface{}{}
+		for _, t := range u.tokens {
+			tokens = append(tokens, tokenModel(u, t))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"api_tokens":   tokens,
+			"oauth_tokens": []interface{}{},
+		})
+
+	case http.MethodPost:
+		var req struct {
+			Note      string   `json:"note"`
+			ExpiresIn int      `json:"expires_in"`
+			Scopes    []string `json:"scopes"`
+			Roles     []string `json:"roles"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		u.nextTokenID++
+		t := &apiToken{
+			id:      fmt.Sprintf("a%d", u.nextTokenID),
+			token:   h.newToken(),
+			note:    req.Note,
+			scopes:  req.Scopes,
+			roles:   req.Roles,
+			created: time.Now(),
+		}
+		if req.ExpiresIn > 0 {
+			t.expires = t.created.Add(time.Duration(req.ExpiresIn) * time.Second)
+		}
+		u.tokens = append(u.tokens, t)
+		h.tokens[t.token] = tokenOwner{user: u.name, id: t.id}
+		m := tokenModel(u, t)
+		m["token"] = t.token
+		writeJSON(w, http.StatusCreated, m)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (h *Hub) serveToken(w http.ResponseWriter, r *http.Request, u *user, id string) {
+	i := -1
+	for j, t := range u.tokens {
+		if t.id == id {
+			i = j
+		}
+	}
+	if i < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No such token: %s", id))
+		return
+	}
+	t := u.tokens[i]
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, tokenModel(u, t))
+	case http.MethodDelete:
+		u.tokens = append(u.tokens[:i], u.tokens[i+1:]...)
+		delete(h.tokens, t.token)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+// serveActivity records the user's, and their servers', activity.
+func (h *Hub) serveActivity(w http.ResponseWriter, r *http.Request, u *user) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	var req struct {
+		LastActivity *time.Time `json:"last_activity"`
+		Servers      map[string]struct {
+			LastActivity *time.Time `json:"last_activity"`
+		} `json:"servers"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.LastActivity == nil {
+		writeError(w, http.StatusBadRequest, "last_activity is required")
+		return
+	}
+	h.refresh(u)
+	for name, a := range req.Servers {
+		s, ok := u.servers[name]
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("No such server '%s' for user %s", name, u.name))
+			return
+		}
+		if a.LastActivity == nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("server %s: last_activity is required", name))
+			return
+		}
+		if a.LastActivity.After(s.lastActivity) {
+			s.lastActivity = *a.LastActivity
+		}
+	}
+	if req.LastActivity.After(u.lastActivity) {
+		u.lastActivity = *req.LastActivity
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveWhoami describes the owner of the request's token.
+func (h *Hub) serveWhoami(w http.ResponseWriter, r *http.Request, caller tokenOwner) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	var m map[string]interface{}
+	if caller.user != "" {
+		m = h.userModel(h.users[caller.user])
+	} else {
+		m = serviceModel(h.services[caller.service])
+	}
+	m["scopes"] = h.scopes(caller)
+	writeJSON(w, http.StatusOK, m)
+}
+
+// scopes are the expanded scopes of the caller's token.
+func (h *Hub) scopes(caller tokenOwner) []string {
+	if h.isAdmin(caller) {
+		return []string{"admin:groups", "admin:servers", "admin:services", "admin:users", "proxy", "read:hub", "shutdown"}
+	}
+	if caller.user == "" {
+		return []string{}
+	}
+	n := caller.user
+	return []string{
+		fmt.Sprintf("access:servers!user=%s", n),
+		fmt.Sprintf("read:users!user=%s", n),
+		fmt.Sprintf("servers!user=%s", n),
+		fmt.Sprintf("tokens!user=%s", n),
+		fmt.Sprintf("users:activity!user=%s", n),
+	}
+}
+
+// isProgress is true for the paths of a server's progress.
+func isProgress(parts []string) bool {
+	return parts[0] == "users" &&
+		((len(parts) == 4 && parts[2] == "server" && parts[3] == "progress") ||
+			(len(parts) == 5 && parts[2] == "servers" && parts[4] == "progress"))
+}
+
+// serverName is the server name in a progress path.
+func serverName(parts []string) string {
+	if parts[2] == "servers" {
+		return parts[3]
+	}
+	return ""
+}
+
+// serveProgress streams the progress of a server's spawn as server-sent events,
+// until it's ready or has failed.
+func (h *Hub) serveProgress(w http.ResponseWriter, r *http.Request, username, servername string) {
+	flusher, _ := w.(http.Flusher)
+	started := false
+	for {
+		h.mu.Lock()
+		event, done, status, message := h.progressEvent(username, servername, started)
+		tick := h.SpawnDelay / 10
+		h.mu.Unlock()
+
+		if status != 0 {
+			writeError(w, status, message)
+			return
+		}
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		b, _ := json.Marshal(event)
+		fmt.Fprintf(w, "data: %s\n\n", b)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			return
+		}
+
+		if tick < 10*time.Millisecond {
+			tick = 10 * time.Millisecond
+		}
+		select {
+		case <-time.After(tick):
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// progressEvent is the next progress event for a server, and whether it's the last one.
+// If there's no progress to report a status and message for the error are returned instead.
+// The hub must be locked.
+func (h *Hub) progressEvent(username, servername string, started bool) (event map[string]interface{}, done bool, status int, message string) {
+	u, ok := h.users[username]
+	if !ok {
+		return nil, true, http.StatusNotFound, fmt.Sprintf("No such user: %s", username)
+	}
+	h.refresh(u)
+	s, running := u.servers[servername]
+	switch {
+	case running && s.ready:
+		url := serverPath(u, servername)
+		return map[string]interface{}{
+			"progress":     100,
+			"ready":        true,
+			"message":      fmt.Sprintf("Server ready at %s", url),
+			"html_message": fmt.Sprintf("Server ready at <a href=\"%s\">%s</a>", url, url),
+			"url":          url,
+		}, true, 0, ""
+	case running && s.pending == "spawn":
+		percent := 0
+		if total := s.readyAt.Sub(s.started); total > 0 {
+			percent = int(time.Since(s.started) * 100 / total)
+		}
+		if percent > 99 {
+			percent = 99
+		}
+		return map[string]interface{}{
+			"progress": percent,
+			"message":  fmt.Sprintf("Spawning server %s...", u.logName(servername)),
+		}, false, 0, ""
+	case u.failures[servername] != "" || started:
+		failure := u.failures[servername]
+		if failure == "" {
+			failure = "server stopped"
+		}
+		return map[string]interface{}{
+			"progress": 100,
+			"failed":   true,
+			"message":  fmt.Sprintf("Spawn failed: %s", failure),
+		}, true, 0, ""
+	}
+	return nil, true, http.StatusBadRequest, fmt.Sprintf("%s is not running", u.logName(servername))
+}